@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+)
+
+var manual = flag.Bool("manual", false, "accept manual (binutils) syntax")
+
+// Assembles insns given as arguments, or one per line from stdin if no
+// argument is given.
+func main() {
+	flag.Parse()
+
+	descs, err := common.ReadAllInsnDescs(common.TablesDir)
+	if err != nil {
+		panic(err)
+	}
+
+	a := common.NewAssembler(descs)
+	if *manual {
+		a = common.NewManualAssembler(descs)
+	}
+
+	lines := flag.Args()
+	if len(lines) == 0 {
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			l := strings.TrimSpace(sc.Text())
+			if len(l) == 0 {
+				continue
+			}
+			lines = append(lines, l)
+		}
+		if err := sc.Err(); err != nil {
+			panic(err)
+		}
+	}
+
+	failed := false
+	for _, l := range lines {
+		word, err := a.Assemble(l)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			failed = true
+			continue
+		}
+
+		fmt.Printf("%08x\t%s\n", word, l)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+)
+
+// Cross-checks the insn descriptions against the opcode tables of binutils,
+// read from the opcodes/loongarch-opc.c given as argument.
+//
+// Entries are matched by manual mnemonic, and their words, masks and
+// operands in manual syntax are compared. Differences and insns missing from
+// either side are reported, and the exit status is 1 if there is any.
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s path/to/loongarch-opc.c\n", os.Args[0])
+		os.Exit(2)
+	}
+	path := os.Args[1]
+
+	descs, err := common.ReadAllInsnDescs(common.TablesDir)
+	if err != nil {
+		panic(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	opcodes, err := common.ParseBinutilsOpcodes(f)
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", path, err))
+	}
+
+	problems := check(filepath.Base(path), descs, opcodes)
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+}
+
+func check(
+	name string,
+	descs []*common.InsnDescription,
+	opcodes []*common.BinutilsOpcode,
+) []string {
+	tr := common.NewTranslator(descs)
+	seen := make(map[*common.InsnDescription]bool)
+
+	var result []string
+	for _, op := range opcodes {
+		if op.IsMacro() {
+			continue
+		}
+
+		report := func(format string, a ...interface{}) {
+			msg := fmt.Sprintf(format, a...)
+			result = append(result, fmt.Sprintf("%s:%d: %s: %s", name, op.Line, op.Name, msg))
+		}
+
+		opFmt, err := common.ParseBinutilsFormat(op.Format)
+		if err != nil {
+			report("%v", err)
+			continue
+		}
+
+		d := tr.LookupManual(op.Name)
+		if d == nil {
+			if isSpecialization(descs, op) {
+				// aliases like "nop" for "andi $zero, $zero, 0"
+				continue
+			}
+
+			if other := findByEncoding(descs, op); other != nil {
+				report("named %s in manual syntax of tables", other.ManualMnemonic())
+				continue
+			}
+
+			report("missing from tables (%08x %s)", op.Match, opFmt.CanonicalRepr())
+			continue
+		}
+
+		if isSpecializationOf(d, op) {
+			seen[d] = true
+			continue
+		}
+
+		seen[d] = true
+
+		if op.Match != d.Word {
+			report("word %08x, tables have %08x", op.Match, d.Word)
+		}
+
+		if mask := d.Format.MatchBitmask(); op.Mask != mask {
+			report("mask %08x, tables have %08x", op.Mask, mask)
+		}
+
+		if a, b := opFmt.CanonicalRepr(), d.ManualFormat().CanonicalRepr(); a != b {
+			report("operands %s, tables have %s", a, b)
+		}
+	}
+
+	for _, d := range descs {
+		if seen[d] {
+			continue
+		}
+
+		result = append(result, fmt.Sprintf(
+			"%s: %s (%08x %s): missing from %s",
+			d.Source,
+			d.ManualMnemonic(),
+			d.Word,
+			d.ManualFormat().CanonicalRepr(),
+			name,
+		))
+	}
+
+	return result
+}
+
+// findByEncoding returns the insn encoded exactly like the entry, if any.
+func findByEncoding(descs []*common.InsnDescription, op *common.BinutilsOpcode) *common.InsnDescription {
+	for _, d := range descs {
+		if d.Word == op.Match && d.Format.MatchBitmask() == op.Mask {
+			return d
+		}
+	}
+	return nil
+}
+
+// isSpecialization reports whether the entry is a specialization of any
+// insn, i.e. it fixes some operands of the insn to constant values.
+func isSpecialization(descs []*common.InsnDescription, op *common.BinutilsOpcode) bool {
+	for _, d := range descs {
+		if isSpecializationOf(d, op) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSpecializationOf(d *common.InsnDescription, op *common.BinutilsOpcode) bool {
+	mask := d.Format.MatchBitmask()
+	return op.Mask != mask && op.Mask&mask == mask && d.Matches(op.Match)
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+)
+
+var selector = flag.String(
+	"select",
+	"",
+	"only report insns of the tables matching this selector, e.g. \"base,fp,lsx,lasx\"",
+)
+
+// Cross-checks the insn descriptions against the LoongArch backend of LLVM,
+// read from the TableGen sources given as arguments. Directories are
+// searched for *.td files.
+//
+// Records are matched by mnemonic, and their words, masks and operand
+// fields are compared. Differences and insns missing from either side are
+// reported, and the exit status is 1 if there is any.
+func main() {
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "usage: %s [-select ...] path/to/LoongArch/*.td\n", os.Args[0])
+		os.Exit(2)
+	}
+
+	var paths []string
+	for _, arg := range flag.Args() {
+		fi, err := os.Stat(arg)
+		if err != nil {
+			panic(err)
+		}
+
+		if !fi.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+
+		tdPaths, err := filepath.Glob(filepath.Join(arg, "*.td"))
+		if err != nil {
+			panic(err)
+		}
+		paths = append(paths, tdPaths...)
+	}
+
+	sel, err := common.ParseSelector(*selector)
+	if err != nil {
+		panic(err)
+	}
+
+	descs, err := common.ReadAllInsnDescs(common.TablesDir)
+	if err != nil {
+		panic(err)
+	}
+
+	insns, err := common.ReadTableGenInsns(paths)
+	if err != nil {
+		panic(err)
+	}
+
+	problems := check(descs, sel, insns)
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+}
+
+// check compares the TableGen records against all descs, only reporting
+// differences of the descs matched by sel.
+func check(
+	descs []*common.InsnDescription,
+	sel *common.Selector,
+	insns []*common.TableGenInsn,
+) []string {
+	tr := common.NewTranslator(descs)
+	dec := common.NewDecoder(descs)
+	seen := make(map[*common.InsnDescription]bool)
+
+	var result []string
+	for _, insn := range insns {
+		report := func(format string, a ...interface{}) {
+			msg := fmt.Sprintf(format, a...)
+			result = append(result, fmt.Sprintf(
+				"%s:%d: %s: %s",
+				filepath.Base(insn.Path),
+				insn.Line,
+				insn.Name,
+				msg,
+			))
+		}
+
+		if insn.Err != nil {
+			report("%v", insn.Err)
+			continue
+		}
+
+		mnemonic := insn.Mnemonic()
+		d := tr.LookupManual(mnemonic)
+		if d == nil {
+			d = tr.Lookup(mnemonic)
+		}
+
+		if d == nil {
+			other, err := dec.Lookup(insn.Word)
+			if err == nil && other.Format.MatchBitmask() == insn.Mask {
+				seen[other] = true
+				if sel.Matches(other) {
+					report("encoded like %s in tables", other.Mnemonic)
+				}
+				continue
+			}
+
+			report("missing from tables (%08x %s)", insn.Word, describeTableGenArgs(insn.Args))
+			continue
+		}
+
+		seen[d] = true
+		if !sel.Matches(d) {
+			continue
+		}
+
+		if insn.Word != d.Word {
+			report("word %08x, tables have %08x", insn.Word, d.Word)
+		}
+
+		if mask := d.Format.MatchBitmask(); insn.Mask != mask {
+			report("mask %08x, tables have %08x", insn.Mask, mask)
+		}
+
+		if !argsAgree(insn.Args, d.Format.Args) {
+			report(
+				"operands %s, tables have %s",
+				describeTableGenArgs(insn.Args),
+				describeArgs(d.Format.Args),
+			)
+		}
+	}
+
+	for _, d := range descs {
+		if seen[d] || !sel.Matches(d) {
+			continue
+		}
+
+		result = append(result, fmt.Sprintf(
+			"%s: %s (%08x %s): missing from LLVM",
+			d.Source,
+			d.Mnemonic,
+			d.Word,
+			d.Format.CanonicalRepr(),
+		))
+	}
+
+	return result
+}
+
+// argsAgree reports whether the operand fields occupy the same slots as the
+// args, with the same kinds where the kinds of the fields are known.
+func argsAgree(tgArgs []*common.TableGenArg, args []*common.Arg) bool {
+	if len(tgArgs) != len(args) {
+		return false
+	}
+
+	for _, a := range args {
+		var match *common.TableGenArg
+		for _, ta := range tgArgs {
+			if ta.Bitmask() == a.Bitmask() {
+				match = ta
+				break
+			}
+		}
+
+		if match == nil || slotsRepr(match.Slots) != slotsRepr(a.Slots) {
+			return false
+		}
+
+		if match.Kind != common.ArgKindUnknown && match.Kind != a.Kind {
+			return false
+		}
+	}
+
+	return true
+}
+
+func slotsRepr(slots []*common.Slot) string {
+	var sb strings.Builder
+	for _, s := range slots {
+		sb.WriteString(s.CanonicalRepr())
+	}
+	return sb.String()
+}
+
+func describeArgs(args []*common.Arg) string {
+	reprs := make([]string, len(args))
+	for i, a := range args {
+		reprs[i] = a.CanonicalRepr()
+	}
+	return "[" + strings.Join(reprs, " ") + "]"
+}
+
+func describeTableGenArgs(args []*common.TableGenArg) string {
+	// mimic the canonical order of args: registers first
+	sorted := make([]*common.TableGenArg, len(args))
+	copy(sorted, args)
+	sort.SliceStable(sorted, func(i int, j int) bool {
+		return !sorted[i].Kind.IsImm() && sorted[j].Kind.IsImm()
+	})
+
+	reprs := make([]string, len(sorted))
+	for i, a := range sorted {
+		if a.Kind == common.ArgKindUnknown {
+			reprs[i] = a.Name + "?" + slotsRepr(a.Slots)
+			continue
+		}
+
+		reprs[i] = (&common.Arg{Kind: a.Kind, Slots: a.Slots}).CanonicalRepr()
+	}
+	return "[" + strings.Join(reprs, " ") + "]"
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+)
+
+// Cross-checks the insn descriptions against the QEMU decodetree files given
+// as arguments, like target/loongarch/insns.decode.
+//
+// Patterns are matched to insns by encoding. Patterns without a matching
+// insn, and insns marked @qemu without a matching pattern are reported, and
+// the exit status is 1 if there is any.
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s path/to/insns.decode...\n", os.Args[0])
+		os.Exit(2)
+	}
+
+	descs, err := common.ReadAllInsnDescs(common.TablesDir)
+	if err != nil {
+		panic(err)
+	}
+
+	var patterns []*sourcedPattern
+	for _, path := range os.Args[1:] {
+		ps, err := readDecodetreeFile(path)
+		if err != nil {
+			panic(err)
+		}
+
+		for _, p := range ps {
+			patterns = append(patterns, &sourcedPattern{
+				pattern: p,
+				path:    filepath.Base(path),
+			})
+		}
+	}
+
+	problems := check(descs, patterns)
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+}
+
+type sourcedPattern struct {
+	pattern *common.DecodetreePattern
+	path    string
+}
+
+func readDecodetreeFile(path string) ([]*common.DecodetreePattern, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	result, err := common.ParseDecodetree(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return result, nil
+}
+
+func check(descs []*common.InsnDescription, patterns []*sourcedPattern) []string {
+	dec := common.NewDecoder(descs)
+	seen := make(map[*common.InsnDescription]bool)
+
+	var result []string
+	for _, sp := range patterns {
+		p := sp.pattern
+		report := func(format string, a ...interface{}) {
+			msg := fmt.Sprintf(format, a...)
+			result = append(result, fmt.Sprintf("%s:%d: %s: %s", sp.path, p.Line, p.Name, msg))
+		}
+
+		d, err := dec.Lookup(p.Word)
+		if err != nil {
+			report("missing from tables (%08x/%08x)", p.Word, p.Mask)
+			continue
+		}
+
+		seen[d] = true
+
+		// patterns may fix more bits than the insn does, e.g. to decode
+		// special cases separately
+		mask := d.Format.MatchBitmask()
+		if p.Mask&mask != mask {
+			report("mask %08x does not cover %s (%08x/%08x)", p.Mask, d.Mnemonic, d.Word, mask)
+		}
+	}
+
+	for _, d := range descs {
+		if !d.Attribs.QEMU || seen[d] {
+			continue
+		}
+
+		result = append(result, fmt.Sprintf(
+			"%s: %s (%08x %s): marked @qemu but not decoded by QEMU",
+			d.Source,
+			d.Mnemonic,
+			d.Word,
+			d.Format.CanonicalRepr(),
+		))
+	}
+
+	return result
+}
@@ -0,0 +1,125 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/registers"
+)
+
+// Assembler encodes single insns written in canonical syntax, i.e. the
+// syntax produced by FormatInsn, or in manual syntax, i.e. the syntax
+// produced by FormatManualInsn.
+type Assembler struct {
+	descs  map[string]*InsnDescription
+	manual bool
+}
+
+func NewAssembler(descs []*InsnDescription) *Assembler {
+	m := make(map[string]*InsnDescription, len(descs))
+	for _, d := range descs {
+		m[d.Mnemonic] = d
+	}
+
+	return &Assembler{
+		descs: m,
+	}
+}
+
+// NewManualAssembler returns an assembler accepting manual syntax, which
+// is also what binutils accepts.
+func NewManualAssembler(descs []*InsnDescription) *Assembler {
+	m := make(map[string]*InsnDescription, len(descs))
+	for _, d := range descs {
+		m[d.ManualMnemonic()] = d
+	}
+
+	return &Assembler{
+		descs:  m,
+		manual: true,
+	}
+}
+
+// Assemble parses one line of assembly like "ld.d $a0, $sp, 16" and
+// returns the encoded insn word.
+func (a *Assembler) Assemble(line string) (uint32, error) {
+	line = strings.TrimSpace(line)
+	mnemonic, operandsStr := line, ""
+	if idx := strings.IndexAny(line, " \t"); idx != -1 {
+		mnemonic, operandsStr = line[:idx], strings.TrimSpace(line[idx+1:])
+	}
+
+	d, ok := a.descs[mnemonic]
+	if !ok {
+		return 0, fmt.Errorf("unknown mnemonic %s", strconv.Quote(mnemonic))
+	}
+
+	var operands []string
+	if len(operandsStr) > 0 {
+		operands = strings.Split(operandsStr, ",")
+	}
+
+	args := d.PostprocessedFormat().Args
+	if a.manual {
+		args = d.ManualFormat().Args
+	}
+
+	if len(operands) != len(args) {
+		return 0, fmt.Errorf(
+			"%s: expected %d operand(s), got %d",
+			mnemonic,
+			len(args),
+			len(operands),
+		)
+	}
+
+	word := d.Word
+	for i, arg := range args {
+		operand := strings.TrimSpace(operands[i])
+
+		var class registers.Class
+		if !arg.Kind.IsImm() {
+			class = arg.Kind.RegClass()
+			if a.manual {
+				class = d.manualRegClass(i)
+			}
+		}
+
+		val, err := parseOperand(arg, class, operand)
+		if err != nil {
+			return 0, fmt.Errorf("%s: operand %d: %w", mnemonic, i+1, err)
+		}
+
+		bits, err := arg.Encode(val)
+		if err != nil {
+			return 0, fmt.Errorf("%s: operand %d: %w", mnemonic, i+1, err)
+		}
+
+		word |= bits
+	}
+
+	return word, nil
+}
+
+func parseOperand(a *Arg, class registers.Class, operand string) (int64, error) {
+	if a.Kind.IsImm() {
+		if strings.HasPrefix(operand, "$") {
+			return 0, fmt.Errorf("expected immediate, got %s", operand)
+		}
+
+		val, err := strconv.ParseInt(operand, 0, 64)
+		if err != nil {
+			return 0, fmt.Errorf("malformed immediate %s", strconv.Quote(operand))
+		}
+
+		return val, nil
+	}
+
+	r, err := class.Parse(operand)
+	if err != nil {
+		return 0, fmt.Errorf("expected %s, got %s", class, operand)
+	}
+
+	return int64(r.Index), nil
+}
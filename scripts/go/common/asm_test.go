@@ -0,0 +1,71 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssemble(t *testing.T) {
+	a := NewAssembler(mustParseInsnDescriptionLines(
+		t,
+		"02c00000 addi.d                 DJSk12",
+		"28c00000 ld.d                   DJSk12",
+		"26000000 ldox4.d                DJSk14          @orig_name=ldptr.d @orig_fmt=DJSk14ps2",
+		"002c0000 sladd.d                DJKUa2          @orig_name=alsl.d @orig_fmt=DJKUa2pp1",
+		"50000000 b                      Sd10k16         @orig_fmt=Sd10k16ps2",
+		"0c100000 fcmp.caf.s             CdFjFk",
+		"2c800000 xvld                   XdJSk12",
+		"06483800 eret                   EMPTY",
+	))
+
+	testcases := []struct {
+		x        string
+		ok       bool
+		expected uint32
+	}{
+		{x: "addi.d $r4, $r5, -12", ok: true, expected: 0x02ffd0a4},
+		{x: "ld.d $a0, $sp, 16", ok: true, expected: 0x28c04064},
+		{x: "  ld.d\t$a0,$sp,0x10  ", ok: true, expected: 0x28c04064},
+		{x: "ldox4.d $r4, $r5, 4", ok: true, expected: 0x260004a4},
+		{x: "sladd.d $r3, $r4, $r5, 2", ok: true, expected: 0x002c9483},
+		{x: "b -4", ok: true, expected: 0x53ffffff},
+		{x: "fcmp.caf.s $fcc7, $fa2, $f3", ok: true, expected: 0x0c100c47},
+		{x: "xvld $xr4, $r5, 1", ok: true, expected: 0x2c8004a4},
+		{x: "eret", ok: true, expected: 0x06483800},
+
+		// unknown mnemonic
+		{x: "foo $r0"},
+		// wrong operand count
+		{x: "addi.d $r4, $r5"},
+		{x: "eret $r0"},
+		// wrong register class
+		{x: "addi.d $f4, $r5, 1"},
+		{x: "xvld $vr4, $r5, 1"},
+		{x: "fcmp.caf.s $f7, $f2, $f3"},
+		// register out of range
+		{x: "addi.d $r32, $r5, 1"},
+		// immediate where register is expected, and vice versa
+		{x: "addi.d 4, $r5, 1"},
+		{x: "addi.d $r4, $r5, $r6"},
+		// immediates out of range
+		{x: "addi.d $r4, $r5, 2048"},
+		{x: "addi.d $r4, $r5, -2049"},
+		{x: "sladd.d $r3, $r4, $r5, 0"},
+		{x: "sladd.d $r3, $r4, $r5, 5"},
+		{x: "b 134217728"},
+		// misaligned offsets
+		{x: "b 2"},
+		{x: "ldox4.d $r4, $r5, 6"},
+	}
+
+	for _, tc := range testcases {
+		actual, err := a.Assemble(tc.x)
+		if tc.ok {
+			assert.NoError(t, err, tc.x)
+			assert.Equal(t, tc.expected, actual, tc.x)
+		} else {
+			assert.Error(t, err, tc.x)
+		}
+	}
+}
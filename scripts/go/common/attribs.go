@@ -0,0 +1,190 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// InsnAttribs are the attributes attached to an insn description, like
+// "@qemu" or "@orig_name=alsl.d".
+//
+// The "@orig_fmt" attribute is not included, but parsed into
+// InsnDescription.OrigFormat instead.
+type InsnAttribs struct {
+	// "@la32": the insn is available in LA32.
+	LA32 bool
+	// "@primary": the insn is part of the LA32 Primary subset.
+	Primary bool
+	// "@qemu": the insn is used by the QEMU TCG backend.
+	QEMU bool
+	// "@lbt": the insn belongs to the LBT extension.
+	LBT bool
+	// "@lvz": the insn belongs to the LVZ extension.
+	LVZ bool
+	// "@provisional": the insn's existence or semantics is not confirmed.
+	Provisional bool
+	// "@orig_name=": the insn's mnemonic in the manual, if different.
+	OrigName string
+	// "@rev=": the ISA revision introducing the insn, zero if the insn is
+	// present since the initial revision.
+	Rev ISARevision
+}
+
+// ISARevision is a revision of the LoongArch ISA manual, like "1p10" for
+// v1.10.
+type ISARevision struct {
+	Major int
+	Minor int
+}
+
+var isaRevisionRE = regexp.MustCompile(`^(0|[1-9][0-9]*)p([0-9]{2})$`)
+
+// ParseISARevision parses an ISA revision in its canonical form, with
+// exactly two digits of minor version, so that it prints back the same.
+func ParseISARevision(s string) (ISARevision, error) {
+	matches := isaRevisionRE.FindStringSubmatch(s)
+	if matches == nil {
+		return ISARevision{}, fmt.Errorf("malformed ISA revision %s", strconv.Quote(s))
+	}
+
+	major, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return ISARevision{}, err
+	}
+
+	minor, err := strconv.Atoi(matches[2])
+	if err != nil {
+		return ISARevision{}, err
+	}
+
+	return ISARevision{
+		Major: major,
+		Minor: minor,
+	}, nil
+}
+
+func (r ISARevision) IsZero() bool {
+	return r == ISARevision{}
+}
+
+// Compare returns -1, 0 or 1 if r is respectively earlier than, same as or
+// later than other.
+func (r ISARevision) Compare(other ISARevision) int {
+	switch {
+	case r.Major < other.Major:
+		return -1
+	case r.Major > other.Major:
+		return 1
+	case r.Minor < other.Minor:
+		return -1
+	case r.Minor > other.Minor:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (r ISARevision) String() string {
+	return fmt.Sprintf("%dp%02d", r.Major, r.Minor)
+}
+
+type parsedInsnAttribs struct {
+	attribs InsnAttribs
+
+	origFmt       string
+	origFmtOffset int
+}
+
+// attribError is an error found at the given offset of the attribs part of
+// an insn description line.
+type attribError struct {
+	offset int
+	err    error
+}
+
+func (e *attribError) Error() string {
+	return e.err.Error()
+}
+
+func (e *attribError) Unwrap() error {
+	return e.err
+}
+
+func parseInsnAttribs(input string) (*parsedInsnAttribs, error) {
+	var result parsedInsnAttribs
+	seen := make(map[string]struct{})
+
+	for _, m := range attribRE.FindAllStringSubmatchIndex(input, -1) {
+		offset := m[0]
+		key := input[m[2]:m[3]]
+		hasValue := m[4] != -1
+		value := ""
+		if hasValue {
+			value = input[m[4]:m[5]]
+		}
+
+		makeErr := func(err error) error {
+			return &attribError{offset: offset, err: err}
+		}
+
+		if _, ok := seen[key]; ok {
+			return nil, makeErr(fmt.Errorf("duplicate attrib @%s", key))
+		}
+		seen[key] = struct{}{}
+
+		var flag *bool
+		switch key {
+		case "la32":
+			flag = &result.attribs.LA32
+		case "primary":
+			flag = &result.attribs.Primary
+		case "qemu":
+			flag = &result.attribs.QEMU
+		case "lbt":
+			flag = &result.attribs.LBT
+		case "lvz":
+			flag = &result.attribs.LVZ
+		case "provisional":
+			flag = &result.attribs.Provisional
+
+		case "orig_name", origFmtKey, "rev":
+			if !hasValue || value == "" {
+				return nil, makeErr(fmt.Errorf("attrib @%s requires a value", key))
+			}
+
+		default:
+			return nil, makeErr(fmt.Errorf("unknown attrib @%s", key))
+		}
+
+		if flag != nil {
+			if hasValue {
+				return nil, makeErr(fmt.Errorf("attrib @%s takes no value", key))
+			}
+			*flag = true
+			continue
+		}
+
+		switch key {
+		case "orig_name":
+			result.attribs.OrigName = value
+
+		case origFmtKey:
+			result.origFmt = value
+			result.origFmtOffset = m[4]
+
+		case "rev":
+			rev, err := ParseISARevision(value)
+			if err != nil {
+				return nil, &attribError{offset: m[4], err: err}
+			}
+			if rev.IsZero() {
+				return nil, &attribError{offset: m[4], err: errors.New("ISA revision must not be zero")}
+			}
+			result.attribs.Rev = rev
+		}
+	}
+
+	return &result, nil
+}
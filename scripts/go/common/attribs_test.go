@@ -0,0 +1,36 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestISARevision(t *testing.T) {
+	v100, err := ParseISARevision("1p00")
+	assert.NoError(t, err)
+	v110, err := ParseISARevision("1p10")
+	assert.NoError(t, err)
+	v200, err := ParseISARevision("2p00")
+	assert.NoError(t, err)
+
+	assert.Equal(t, ISARevision{Major: 1, Minor: 10}, v110)
+	assert.Equal(t, "1p10", v110.String())
+	assert.Equal(t, "2p00", v200.String())
+
+	assert.Equal(t, -1, v100.Compare(v110))
+	assert.Equal(t, 1, v200.Compare(v110))
+	assert.Equal(t, 0, v110.Compare(v110))
+	assert.Equal(t, -1, ISARevision{}.Compare(v100))
+
+	for _, s := range []string{"1p00", "1p10", "1p01", "2p00", "10p99"} {
+		r, err := ParseISARevision(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, s, r.String())
+	}
+
+	for _, s := range []string{"", "1", "1p", "p10", "1.10", "v1p10", "1p1", "1p100", "01p10"} {
+		_, err := ParseISARevision(s)
+		assert.Error(t, err, s)
+	}
+}
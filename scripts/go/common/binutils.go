@@ -0,0 +1,233 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/registers"
+)
+
+// BinutilsOpcode is an entry of the opcode tables in binutils'
+// opcodes/loongarch-opc.c, like
+//
+//	{ 0x00100000, 0xffff8000, "add.w", "r0:5,r5:5,r10:5", 0, 0, 0, 0 },
+type BinutilsOpcode struct {
+	Match  uint32
+	Mask   uint32
+	Name   string
+	Format string
+	// Line is the 1-based line number of the entry in the source file.
+	Line int
+}
+
+// IsMacro reports whether the entry is an assembler macro instead of an
+// actual insn.
+func (o *BinutilsOpcode) IsMacro() bool {
+	return o.Mask == 0
+}
+
+var binutilsOpcodeRE = regexp.MustCompile(
+	`^\s*\{\s*(0x[0-9A-Fa-f]+|0)\s*,\s*(0x[0-9A-Fa-f]+|0)\s*,\s*"([^"]*)"\s*,\s*"([^"]*)"`,
+)
+
+// ParseBinutilsOpcodes extracts all opcode table entries from the source of
+// binutils' opcodes/loongarch-opc.c. Lines not looking like entries are
+// ignored.
+func ParseBinutilsOpcodes(r io.Reader) ([]*BinutilsOpcode, error) {
+	var result []*BinutilsOpcode
+
+	sc := bufio.NewScanner(r)
+	lineNum := 0
+	for sc.Scan() {
+		lineNum++
+
+		m := binutilsOpcodeRE.FindStringSubmatch(sc.Text())
+		if m == nil {
+			continue
+		}
+
+		match, err := strconv.ParseUint(m[1], 0, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: malformed match: %w", lineNum, err)
+		}
+
+		mask, err := strconv.ParseUint(m[2], 0, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: malformed mask: %w", lineNum, err)
+		}
+
+		result = append(result, &BinutilsOpcode{
+			Match:  uint32(match),
+			Mask:   uint32(mask),
+			Name:   m[3],
+			Format: m[4],
+			Line:   lineNum,
+		})
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+var binutilsOperandRE = regexp.MustCompile(
+	`^([a-z]+)((?:[0-9]+:[0-9]+)(?:\|[0-9]+:[0-9]+)*)(?:<<([0-9]+)|\+([0-9]+))?$`,
+)
+
+// binutils operand kinds, by the leading letters of operand specs
+var binutilsArgKinds = map[string]ArgKind{
+	"r":  ArgKindIntReg,
+	"f":  ArgKindFPReg,
+	"c":  ArgKindFCCReg,
+	"cr": ArgKindScratchReg,
+	// FCSR operands are integer registers in the manual syntax
+	"fc": ArgKindIntReg,
+	"v":  ArgKindVReg,
+	"x":  ArgKindXReg,
+	"s":  ArgKindSignedImm,
+	"sb": ArgKindSignedImm,
+	// offsets of ll, sc, ldptr and stptr
+	"so": ArgKindSignedImm,
+	"u":  ArgKindUnsignedImm,
+}
+
+// ParseBinutilsFormat parses the operand specs of a binutils opcode entry,
+// like "r0:5,r5:5,sb10:16<<2", into an insn format in manual syntax.
+//
+// Slots of an operand are listed from MSB to LSB, like in insn formats of
+// this project, e.g. "sb0:10|10:16<<2" is Sd10k16ps2.
+func ParseBinutilsFormat(s string) (*InsnFormat, error) {
+	var args []*Arg
+	if s != "" {
+		for _, spec := range strings.Split(s, ",") {
+			a, err := parseBinutilsOperand(spec)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, a)
+		}
+	}
+
+	result := &InsnFormat{
+		Args: args,
+	}
+
+	err := result.ValidateManualSyntax()
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func parseBinutilsOperand(spec string) (*Arg, error) {
+	m := binutilsOperandRE.FindStringSubmatch(spec)
+	if m == nil {
+		return nil, fmt.Errorf("malformed operand spec %s", strconv.Quote(spec))
+	}
+
+	kind, ok := binutilsArgKinds[m[1]]
+	if !ok {
+		return nil, fmt.Errorf("unknown operand kind %s", strconv.Quote(m[1]))
+	}
+
+	var slots []*Slot
+	for _, slotSpec := range strings.Split(m[2], "|") {
+		offsetStr, widthStr, _ := strings.Cut(slotSpec, ":")
+
+		offset, err := strconv.ParseUint(offsetStr, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("malformed operand spec %s: %w", strconv.Quote(spec), err)
+		}
+
+		width, err := strconv.ParseUint(widthStr, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("malformed operand spec %s: %w", strconv.Quote(spec), err)
+		}
+
+		slots = append(slots, &Slot{
+			Offset: uint(offset),
+			Width:  uint(width),
+		})
+	}
+
+	var post PostprocessOp
+	switch {
+	case m[3] != "":
+		amount, _ := strconv.Atoi(m[3])
+		post = PostprocessOp{Kind: PostprocessOpKindShl, Amount: amount}
+	case m[4] != "":
+		amount, _ := strconv.Atoi(m[4])
+		post = PostprocessOp{Kind: PostprocessOpKindAdd, Amount: amount}
+	}
+
+	return &Arg{
+		Kind:  kind,
+		Slots: slots,
+		Post:  post,
+	}, nil
+}
+
+// binutils operand kind letters, by arg kind
+var binutilsKindLetters = map[ArgKind]string{
+	ArgKindIntReg:      "r",
+	ArgKindFPReg:       "f",
+	ArgKindFCCReg:      "c",
+	ArgKindScratchReg:  "cr",
+	ArgKindVReg:        "v",
+	ArgKindXReg:        "x",
+	ArgKindSignedImm:   "s",
+	ArgKindUnsignedImm: "u",
+}
+
+// BinutilsFormat returns the operand specs of the insn for binutils'
+// opcode tables, like "r5:5,r0:5,sb10:16<<2". It is the inverse of
+// ParseBinutilsFormat.
+//
+// Operands are in manual order. FCSR operands are "fc", and the targets of
+// PC-relative branches are "sb", so that binutils resolves them against labels.
+func (d *InsnDescription) BinutilsFormat() string {
+	var targetMask uint32
+	if i := d.BranchTargetIndex(); i >= 0 {
+		targetMask = d.Format.Args[i].Bitmask()
+	}
+
+	args := d.ManualFormat().Args
+	specs := make([]string, len(args))
+	for i, a := range args {
+		var sb strings.Builder
+
+		switch {
+		case !a.Kind.IsImm() && d.manualRegClass(i) == registers.ClassFCSR:
+			sb.WriteString("fc")
+		case a.Bitmask() == targetMask:
+			sb.WriteString("sb")
+		default:
+			sb.WriteString(binutilsKindLetters[a.Kind])
+		}
+
+		for j, s := range a.Slots {
+			if j > 0 {
+				sb.WriteRune('|')
+			}
+			fmt.Fprintf(&sb, "%d:%d", s.Offset, s.Width)
+		}
+
+		switch a.Post.Kind {
+		case PostprocessOpKindAdd:
+			fmt.Fprintf(&sb, "+%d", a.Post.Amount)
+		case PostprocessOpKindShl:
+			fmt.Fprintf(&sb, "<<%d", a.Post.Amount)
+		}
+
+		specs[i] = sb.String()
+	}
+
+	return strings.Join(specs, ",")
+}
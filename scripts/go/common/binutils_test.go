@@ -0,0 +1,169 @@
+package common
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBinutilsOpcodes(t *testing.T) {
+	src := `static struct loongarch_opcode loongarch_fix_opcodes[] =
+{
+  /* match,	mask,		name,		format,				macro,	include, exclude, pinfo.  */
+  { 0x0,	0x0,		"li.w",		"r,sc",				"...",	0, 0, 0 },
+  { 0x00100000,	0xffff8000,	"add.w",	"r0:5,r5:5,r10:5",		0,	0, 0, 0 },
+  { 0x06483800,	0xffffffff,	"ertn",		"",				0,	0, 0, 0 },
+  { 0 } /* Terminate the list.  */
+};
+`
+
+	actual, err := ParseBinutilsOpcodes(strings.NewReader(src))
+	assert.NoError(t, err)
+	assert.Equal(t, []*BinutilsOpcode{
+		{Match: 0, Mask: 0, Name: "li.w", Format: "r,sc", Line: 4},
+		{Match: 0x00100000, Mask: 0xffff8000, Name: "add.w", Format: "r0:5,r5:5,r10:5", Line: 5},
+		{Match: 0x06483800, Mask: 0xffffffff, Name: "ertn", Format: "", Line: 6},
+	}, actual)
+
+	assert.True(t, actual[0].IsMacro())
+	assert.False(t, actual[1].IsMacro())
+}
+
+func TestParseBinutilsFormat(t *testing.T) {
+	testcases := []struct {
+		x        string
+		ok       bool
+		expected string
+	}{
+		{x: "", ok: true, expected: "EMPTY"},
+		{x: "r0:5,r5:5,r10:5", ok: true, expected: "DJK"},
+		{x: "r5:5,r0:5,sb10:16<<2", ok: true, expected: "JDSk16ps2"},
+		{x: "sb0:10|10:16<<2", ok: true, expected: "Sd10k16ps2"},
+		{x: "c5:3,sb0:5|10:16<<2", ok: true, expected: "CjSd5k16ps2"},
+		{x: "r0:5,r5:5,r10:5,u15:2+1", ok: true, expected: "DJKUa2pp1"},
+		{x: "fc0:5,r5:5", ok: true, expected: "DJ"},
+		{x: "cr0:2,r5:5", ok: true, expected: "TdJ"},
+		{x: "f0:5,f5:5,f10:5,f15:5", ok: true, expected: "FdFjFkFa"},
+		{x: "v0:5,r5:5,s10:8<<3,u18:1", ok: true, expected: "VdJSk8ps3Un1"},
+		{x: "x0:5,x5:5,x10:5", ok: true, expected: "XdXjXk"},
+		{x: "r0:5,r5:5,so10:14<<2", ok: true, expected: "DJSk14ps2"},
+
+		{x: "r,sc"},
+		{x: "q0:5"},
+		{x: "r0:5,r0:5"},
+		{x: "r0:5,"},
+		{x: "c0:5"},
+	}
+
+	for _, tc := range testcases {
+		actual, err := ParseBinutilsFormat(tc.x)
+		if tc.ok {
+			assert.NoError(t, err, tc.x)
+			assert.Equal(t, tc.expected, actual.CanonicalRepr(), tc.x)
+		} else {
+			assert.Error(t, err, tc.x)
+		}
+	}
+}
+
+// an excerpt of loongarch_load_store_opcodes in binutils'
+// opcodes/loongarch-opc.c
+const binutilsLoadStoreExcerpt = `static struct loongarch_opcode loongarch_load_store_opcodes[] =
+{
+  /* match,	mask,		name,		format,				macro,			include, exclude, pinfo.  */
+  { 0x20000000, 0xff000000,	"ll.w",		"r0:5,r5:5,so10:14<<2",		0,			0,	0,	0 },
+  { 0x21000000, 0xff000000,	"sc.w",		"r0:5,r5:5,so10:14<<2",		0,			0,	0,	0 },
+  { 0x22000000, 0xff000000,	"ll.d",		"r0:5,r5:5,so10:14<<2",		0,			0,	0,	0 },
+  { 0x23000000, 0xff000000,	"sc.d",		"r0:5,r5:5,so10:14<<2",		0,			0,	0,	0 },
+  { 0x24000000, 0xff000000,	"ldptr.w",	"r0:5,r5:5,so10:14<<2",		0,			0,	0,	0 },
+  { 0x25000000, 0xff000000,	"stptr.w",	"r0:5,r5:5,so10:14<<2",		0,			0,	0,	0 },
+  { 0x26000000, 0xff000000,	"ldptr.d",	"r0:5,r5:5,so10:14<<2",		0,			0,	0,	0 },
+  { 0x27000000, 0xff000000,	"stptr.d",	"r0:5,r5:5,so10:14<<2",		0,			0,	0,	0 },
+  { 0x28000000, 0xffc00000,	"ld.b",		"r0:5,r5:5,s10:12",		0,			0,	0,	0 },
+  { 0x38000000, 0xffff8000,	"ldx.b",	"r0:5,r5:5,r10:5",		0,			0,	0,	0 },
+  { 0x0,	0x0,		"ld.b",		"r,r,s",			"ld.b %1,%2,%3",	0,	0,	0 },
+  { 0 } /* Terminate the list.  */
+};
+`
+
+// TestParseBinutilsUpstream checks the entries of an excerpt of the
+// upstream opcode tables against the real insn tables.
+func TestParseBinutilsUpstream(t *testing.T) {
+	// tests are run in the package directory, one level below the commands
+	descs, err := ReadAllInsnDescs(filepath.Join("..", TablesDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	descsByWord := make(map[uint32]*InsnDescription)
+	for _, d := range descs {
+		descsByWord[d.Word] = d
+	}
+
+	opcodes, err := ParseBinutilsOpcodes(strings.NewReader(binutilsLoadStoreExcerpt))
+	assert.NoError(t, err)
+	assert.Len(t, opcodes, 11)
+
+	for _, op := range opcodes {
+		if op.IsMacro() {
+			continue
+		}
+
+		d, ok := descsByWord[op.Match]
+		if !assert.True(t, ok, op.Name) {
+			continue
+		}
+
+		f, err := ParseBinutilsFormat(op.Format)
+		if !assert.NoError(t, err, op.Name) {
+			continue
+		}
+
+		assert.Equal(t, op.Name, d.ManualMnemonic())
+		assert.Equal(t, op.Mask, d.Format.MatchBitmask(), op.Name)
+		assert.Equal(t, d.ManualFormat().CanonicalRepr(), f.CanonicalRepr(), op.Name)
+	}
+}
+
+func TestBinutilsFormat(t *testing.T) {
+	descs := mustParseInsnDescriptionLines(
+		t,
+		"00100000 add.w                  DJK",
+		"06483800 eret                   EMPTY           @orig_name=ertn",
+		"58000000 beq                    DJSk16          @orig_fmt=JDSk16ps2",
+		"50000000 b                      Sd10k16         @orig_fmt=Sd10k16ps2",
+		"48000000 bceqz                  CjSd5k16        @orig_fmt=CjSd5k16ps2",
+		"4c000000 jirl                   DJSk16          @orig_fmt=DJSk16ps2",
+		// not a PC-relative branch despite the format
+		"4c000000 bar                    DJSk16          @orig_fmt=DJSk16ps2",
+		"00040000 sladd.w                DJKUa2          @orig_name=alsl.w @orig_fmt=DJKUa2pp1",
+		"0114c000 fcsrwr                 JUd5            @orig_name=movgr2fcsr @orig_fmt=DJ",
+		"0114c800 fcsrrd                 DUj5            @orig_name=movfcsr2gr @orig_fmt=DJ",
+		"31100000 vstelm.d               VdJSk8Un1       @orig_fmt=VdJSk8ps3Un1",
+	)
+
+	expected := []string{
+		"r0:5,r5:5,r10:5",
+		"",
+		"r5:5,r0:5,sb10:16<<2",
+		"sb0:10|10:16<<2",
+		"c5:3,sb0:5|10:16<<2",
+		"r0:5,r5:5,s10:16<<2",
+		"r0:5,r5:5,s10:16<<2",
+		"r0:5,r5:5,r10:5,u15:2+1",
+		"fc0:5,r5:5",
+		"r0:5,fc5:5",
+		"v0:5,r5:5,s10:8<<3,u18:1",
+	}
+
+	for i, d := range descs {
+		actual := d.BinutilsFormat()
+		assert.Equal(t, expected[i], actual, d.Mnemonic)
+
+		f, err := ParseBinutilsFormat(actual)
+		assert.NoError(t, err, d.Mnemonic)
+		assert.Equal(t, d.ManualFormat().CanonicalRepr(), f.CanonicalRepr(), d.Mnemonic)
+	}
+}
@@ -0,0 +1,133 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrNoMatch        = errors.New("no insn matches the word")
+	ErrAmbiguousMatch = errors.New("more than one insn matches the word")
+)
+
+// Decoder maps insn words back to their descriptions.
+type Decoder struct {
+	groups []*decoderGroup
+}
+
+// decoderGroup holds all descriptions sharing the same match bitmask, keyed
+// by their word.
+type decoderGroup struct {
+	mask  uint32
+	descs map[uint32][]*InsnDescription
+}
+
+func NewDecoder(descs []*InsnDescription) *Decoder {
+	// most specific masks first, so the order of candidates reported in
+	// errors is deterministic
+	groups := GroupByMatchBitmask(descs)
+
+	result := make([]*decoderGroup, len(groups))
+	for i, g := range groups {
+		dg := &decoderGroup{
+			mask:  g.Mask,
+			descs: make(map[uint32][]*InsnDescription),
+		}
+		for _, d := range g.Descs {
+			dg.descs[d.Word] = append(dg.descs[d.Word], d)
+		}
+		result[i] = dg
+	}
+
+	return &Decoder{
+		groups: result,
+	}
+}
+
+// InsnGroup holds all descriptions sharing the same match bitmask.
+type InsnGroup struct {
+	Mask  uint32
+	Descs []*InsnDescription
+}
+
+// GroupByMatchBitmask groups the descriptions by their match bitmask.
+//
+// Groups of the most specific masks come first, and groups of equally
+// specific masks are sorted by mask, so a decoder trying the groups in order
+// finds the most specific match first. Descriptions keep their relative
+// order within each group.
+func GroupByMatchBitmask(descs []*InsnDescription) []*InsnGroup {
+	groupsByMask := make(map[uint32]*InsnGroup)
+	for _, d := range descs {
+		mask := d.Format.MatchBitmask()
+
+		g, ok := groupsByMask[mask]
+		if !ok {
+			g = &InsnGroup{Mask: mask}
+			groupsByMask[mask] = g
+		}
+
+		g.Descs = append(g.Descs, d)
+	}
+
+	groups := make([]*InsnGroup, 0, len(groupsByMask))
+	for _, g := range groupsByMask {
+		groups = append(groups, g)
+	}
+
+	sort.Slice(groups, func(i int, j int) bool {
+		pi := bits.OnesCount32(groups[i].Mask)
+		pj := bits.OnesCount32(groups[j].Mask)
+		if pi != pj {
+			return pi > pj
+		}
+		return groups[i].Mask < groups[j].Mask
+	})
+
+	return groups
+}
+
+// Lookup returns the description of the only insn matching word.
+//
+// The returned error wraps ErrNoMatch if no insn matches, and
+// ErrAmbiguousMatch if more than one does.
+func (d *Decoder) Lookup(word uint32) (*InsnDescription, error) {
+	var candidates []*InsnDescription
+	for _, g := range d.groups {
+		candidates = append(candidates, g.descs[word&g.mask]...)
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("%08x: %w", word, ErrNoMatch)
+	case 1:
+		return candidates[0], nil
+	}
+
+	mnemonics := make([]string, len(candidates))
+	for i, c := range candidates {
+		mnemonics[i] = c.Mnemonic
+	}
+
+	return nil, fmt.Errorf(
+		"%08x: %w: %s",
+		word,
+		ErrAmbiguousMatch,
+		strings.Join(mnemonics, ", "),
+	)
+}
+
+// Decode looks up the insn matching word, and extracts its operands in
+// canonical order. Operands are postprocessed values; see
+// InsnDescription.Decode.
+func (d *Decoder) Decode(word uint32) (*InsnDescription, []int64, error) {
+	desc, err := d.Lookup(word)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return desc, desc.PostprocessedFormat().Decode(word), nil
+}
@@ -0,0 +1,132 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParseInsnDescriptionLines(t *testing.T, lines ...string) []*InsnDescription {
+	result := make([]*InsnDescription, len(lines))
+	for i, l := range lines {
+		d, err := ParseInsnDescriptionLine(l)
+		if err != nil {
+			t.Fatalf("bad insn description line %q: %v", l, err)
+		}
+		result[i] = d
+	}
+	return result
+}
+
+func TestDecoder(t *testing.T) {
+	descs := mustParseInsnDescriptionLines(
+		t,
+		"00100000 add.w                  DJK",
+		"02c00000 addi.d                 DJSk12",
+		"40000000 beqz                   JSd5k16",
+		"06483800 eret                   EMPTY",
+		"31100000 vstelm.d               VdJSk8Un1",
+		// overlaps with eret
+		"06483800 foo                    EMPTY",
+		"06480000 bar                    Uj5",
+	)
+	d := NewDecoder(descs[:5])
+	dAmbiguous := NewDecoder(descs)
+
+	testcases := []struct {
+		d                *Decoder
+		word             uint32
+		expectedMnemonic string
+		expectedOperands []int64
+		expectedErr      error
+	}{
+		{
+			d:                d,
+			word:             0x00101483,
+			expectedMnemonic: "add.w",
+			expectedOperands: []int64{3, 4, 5},
+		},
+		{
+			d:                d,
+			word:             0x02ffd0a4,
+			expectedMnemonic: "addi.d",
+			expectedOperands: []int64{4, 5, -12},
+		},
+		{
+			// beqz $r4, with offset 0x10_0004 in the Sd5k16 slots
+			d:                d,
+			word:             0x40001090,
+			expectedMnemonic: "beqz",
+			expectedOperands: []int64{4, -0x100000 + 4},
+		},
+		{
+			d:                d,
+			word:             0x06483800,
+			expectedMnemonic: "eret",
+			expectedOperands: []int64{},
+		},
+		{
+			d:                d,
+			word:             0x3117fc41,
+			expectedMnemonic: "vstelm.d",
+			expectedOperands: []int64{1, 2, -1, 1},
+		},
+		{
+			d:           d,
+			word:        0xffffffff,
+			expectedErr: ErrNoMatch,
+		},
+		{
+			d:           dAmbiguous,
+			word:        0x06483800,
+			expectedErr: ErrAmbiguousMatch,
+		},
+	}
+
+	for _, tc := range testcases {
+		desc, operands, err := tc.d.Decode(tc.word)
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Nil(t, desc)
+			continue
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expectedMnemonic, desc.Mnemonic)
+		assert.Equal(t, tc.expectedOperands, operands)
+	}
+}
+
+func TestGroupByMatchBitmask(t *testing.T) {
+	descs := mustParseInsnDescriptionLines(
+		t,
+		"02c00000 addi.d                 DJSk12",
+		"00100000 add.w                  DJK",
+		"06483800 eret                   EMPTY",
+		"00108000 add.d                  DJK",
+		"40000000 beqz                   JSd5k16",
+		"02800000 addi.w                 DJSk12",
+	)
+
+	groups := GroupByMatchBitmask(descs)
+
+	var masks []uint32
+	var mnemonics [][]string
+	for _, g := range groups {
+		masks = append(masks, g.Mask)
+
+		var ms []string
+		for _, d := range g.Descs {
+			ms = append(ms, d.Mnemonic)
+		}
+		mnemonics = append(mnemonics, ms)
+	}
+
+	assert.Equal(t, []uint32{0xffffffff, 0xffff8000, 0xffc00000, 0xfc000000}, masks)
+	assert.Equal(t, [][]string{
+		{"eret"},
+		{"add.w", "add.d"},
+		{"addi.d", "addi.w"},
+		{"beqz"},
+	}, mnemonics)
+}
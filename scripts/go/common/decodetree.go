@@ -0,0 +1,181 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DecodetreePattern is an insn pattern of a QEMU decodetree file, like
+//
+//	add_w            0000 00000001 00000 ..... ..... .....    @rrr
+type DecodetreePattern struct {
+	Name string
+	// Word and Mask are the fixed bits of the pattern, including those
+	// fixed by its format.
+	Word uint32
+	Mask uint32
+	// Format is the name of the format referenced, without the "@" sigil,
+	// or empty if none.
+	Format string
+	// Line is the 1-based line number of the pattern in the source file.
+	Line int
+}
+
+type decodetreeFormat struct {
+	word uint32
+	mask uint32
+}
+
+var decodetreeBitsRE = regexp.MustCompile(`^(?:[01.\-]+|[A-Za-z_][0-9A-Za-z_]*:s?[0-9]+)$`)
+var decodetreeInlineFieldRE = regexp.MustCompile(`^[A-Za-z_][0-9A-Za-z_]*:s?([0-9]+)$`)
+
+// ParseDecodetree extracts all insn patterns from the source of a QEMU
+// decodetree file, like target/loongarch/insns.decode.
+//
+// Field and argument set definitions are not interpreted, and pattern
+// groups are flattened. Insns are assumed to be 32 bits wide.
+func ParseDecodetree(r io.Reader) ([]*DecodetreePattern, error) {
+	formats := make(map[string]*decodetreeFormat)
+	var result []*DecodetreePattern
+
+	sc := bufio.NewScanner(r)
+	lineNum := 0
+	var l string
+	startLineNum := 0
+	for sc.Scan() {
+		lineNum++
+
+		text := sc.Text()
+		if idx := strings.IndexByte(text, '#'); idx != -1 {
+			text = text[:idx]
+		}
+
+		if l == "" {
+			startLineNum = lineNum
+		}
+
+		// join continuation lines
+		if strings.HasSuffix(strings.TrimSpace(text), "\\") {
+			text = strings.TrimSpace(text)
+			l += text[:len(text)-1] + " "
+			continue
+		}
+		l += text
+
+		tokens := strings.Fields(l)
+		l = ""
+		if len(tokens) == 0 {
+			continue
+		}
+
+		makeErr := func(err error) error {
+			return fmt.Errorf("line %d: %w", startLineNum, err)
+		}
+
+		switch tokens[0][0] {
+		case '%', '&':
+			// field and argument set definitions
+			continue
+		case '{', '}', '[', ']':
+			// pattern groups
+			continue
+		}
+
+		word, mask, attrs, err := parseDecodetreeBits(tokens[1:])
+		if err != nil {
+			return nil, makeErr(fmt.Errorf("%s: %w", tokens[0], err))
+		}
+
+		if tokens[0][0] == '@' {
+			formats[tokens[0][1:]] = &decodetreeFormat{
+				word: word,
+				mask: mask,
+			}
+			continue
+		}
+
+		p := &DecodetreePattern{
+			Name: tokens[0],
+			Word: word,
+			Mask: mask,
+			Line: startLineNum,
+		}
+
+		for _, a := range attrs {
+			if !strings.HasPrefix(a, "@") {
+				continue
+			}
+
+			f, ok := formats[a[1:]]
+			if !ok {
+				return nil, makeErr(fmt.Errorf("%s: unknown format %s", p.Name, a))
+			}
+
+			if (p.Word^f.word)&p.Mask&f.mask != 0 {
+				return nil, makeErr(fmt.Errorf("%s: fixed bits conflict with format %s", p.Name, a))
+			}
+
+			p.Format = a[1:]
+			p.Word |= f.word
+			p.Mask |= f.mask
+		}
+
+		result = append(result, p)
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// parseDecodetreeBits parses the leading bit tokens of a pattern or format
+// definition, MSB first, returning the fixed bits and the remaining tokens.
+func parseDecodetreeBits(tokens []string) (uint32, uint32, []string, error) {
+	var word, mask uint32
+	n := 0
+
+	consume := func(width int, fixed bool, val uint32) {
+		word <<= width
+		mask <<= width
+		if fixed {
+			word |= val
+			mask |= 1<<width - 1
+		}
+		n += width
+	}
+
+	i := 0
+	for ; i < len(tokens) && decodetreeBitsRE.MatchString(tokens[i]); i++ {
+		if m := decodetreeInlineFieldRE.FindStringSubmatch(tokens[i]); m != nil {
+			width, _ := strconv.Atoi(m[1])
+			if width > 32 {
+				return 0, 0, nil, fmt.Errorf("field %s too wide", tokens[i])
+			}
+			consume(width, false, 0)
+			continue
+		}
+
+		for _, c := range tokens[i] {
+			switch c {
+			case '0':
+				consume(1, true, 0)
+			case '1':
+				consume(1, true, 1)
+			default:
+				consume(1, false, 0)
+			}
+		}
+	}
+
+	if n != 32 {
+		return 0, 0, nil, fmt.Errorf("expected 32 bits, got %d", n)
+	}
+
+	return word, mask, tokens[i:], nil
+}
@@ -0,0 +1,61 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDecodetree(t *testing.T) {
+	src := `#
+# Fields
+#
+%offs21     0:s5 10:16
+
+&rrr          rd rj rk
+&c_offs       cj offs
+
+@rrr               .... ........ ..... rk:5 rj:5 rd:5    &rrr
+@c_offs21   .... .. ................ .. cj:3 .....    &c_offs offs=%offs21
+@rr_i12      .... ...... imm:s12 rj:5 rd:5    &rr_i
+
+add_w            0000 00000001 00000 ..... ..... .....    @rrr # comment
+bceqz            0100 10 ................ 00 ... .....    @c_offs21
+{
+  nop            0000 001101 000000000000 00000 00000
+  andi           0000 001101 ............ ..... .....     \
+                     @rr_i12
+}
+ertn             0000 01100100 10000 01110 00000 00000
+`
+
+	actual, err := ParseDecodetree(strings.NewReader(src))
+	assert.NoError(t, err)
+	assert.Equal(t, []*DecodetreePattern{
+		{Name: "add_w", Word: 0x00100000, Mask: 0xffff8000, Format: "rrr", Line: 13},
+		{Name: "bceqz", Word: 0x48000000, Mask: 0xfc000300, Format: "c_offs21", Line: 14},
+		{Name: "nop", Word: 0x03400000, Mask: 0xffffffff, Line: 16},
+		{Name: "andi", Word: 0x03400000, Mask: 0xffc00000, Format: "rr_i12", Line: 17},
+		{Name: "ertn", Word: 0x06483800, Mask: 0xffffffff, Line: 20},
+	}, actual)
+}
+
+func TestParseDecodetreeErrors(t *testing.T) {
+	testcases := []string{
+		// too few bits
+		"add_w 0000 00000001 00000 ..... ..... ....\n",
+		// too many bits
+		"add_w 0000 00000001 00000 ..... rj:5 rd:5 .\n",
+		// unknown format
+		"add_w 0000 00000001 00000 ..... ..... .....    @rrr\n",
+		// conflicting fixed bits
+		"@fmt 1... ........ ..... ..... ..... .....\n" +
+			"add_w 0000 00000001 00000 ..... ..... .....    @fmt\n",
+	}
+
+	for _, src := range testcases {
+		_, err := ParseDecodetree(strings.NewReader(src))
+		assert.Error(t, err, src)
+	}
+}
@@ -0,0 +1,73 @@
+package common
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FormatInsn renders the insn with the given canonically-ordered operands
+// in canonical syntax, e.g. "addi.d $r4, $r5, -12".
+//
+// Operands are postprocessed values as returned by InsnDescription.Decode,
+// so that branch offsets and the like are shown as byte values.
+func FormatInsn(d *InsnDescription, operands []int64) string {
+	args := d.Format.Args
+	texts := make([]string, len(args))
+	for i, a := range args {
+		if a.Kind.IsImm() {
+			texts[i] = strconv.FormatInt(operands[i], 10)
+			continue
+		}
+
+		texts[i] = a.Kind.RegClass().Reg(int(operands[i])).Name()
+	}
+
+	return joinInsnText(d.Mnemonic, texts)
+}
+
+// FormatManualInsn renders the insn with the given canonically-ordered
+// operands in manual syntax, like binutils does, e.g. "blt $a1, $a0, 8" for
+// the canonical "bgt $r4, $r5, 8".
+func FormatManualInsn(d *InsnDescription, operands []int64) string {
+	manualOperands := d.ToManualOperands(operands)
+
+	args := d.ManualFormat().Args
+	texts := make([]string, len(args))
+	for i, a := range args {
+		if a.Kind.IsImm() {
+			texts[i] = strconv.FormatInt(manualOperands[i], 10)
+			continue
+		}
+
+		texts[i] = d.manualRegClass(i).Reg(int(manualOperands[i])).ABIName()
+	}
+
+	return joinInsnText(d.ManualMnemonic(), texts)
+}
+
+func joinInsnText(mnemonic string, operands []string) string {
+	if len(operands) == 0 {
+		return mnemonic
+	}
+	return mnemonic + " " + strings.Join(operands, ", ")
+}
+
+// Disassemble decodes word and renders it in canonical syntax.
+func (d *Decoder) Disassemble(word uint32) (string, error) {
+	desc, operands, err := d.Decode(word)
+	if err != nil {
+		return "", err
+	}
+
+	return FormatInsn(desc, operands), nil
+}
+
+// DisassembleManual decodes word and renders it in manual syntax.
+func (d *Decoder) DisassembleManual(word uint32) (string, error) {
+	desc, operands, err := d.Decode(word)
+	if err != nil {
+		return "", err
+	}
+
+	return FormatManualInsn(desc, operands), nil
+}
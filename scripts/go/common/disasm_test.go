@@ -0,0 +1,45 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisassemble(t *testing.T) {
+	d := NewDecoder(mustParseInsnDescriptionLines(
+		t,
+		"02c00000 addi.d                 DJSk12",
+		"26000000 ldox4.d                DJSk14          @orig_name=ldptr.d @orig_fmt=DJSk14ps2",
+		"002c0000 sladd.d                DJKUa2          @orig_name=alsl.d @orig_fmt=DJKUa2pp1",
+		"50000000 b                      Sd10k16         @orig_fmt=Sd10k16ps2",
+		"48000000 bceqz                  CjSd5k16        @orig_fmt=CjSd5k16ps2",
+		"00000800 movgr2scr              TdJ",
+		"0c100000 fcmp.caf.s             CdFjFk",
+		"2c800000 xvld                   XdJSk12",
+		"31100000 vstelm.d               VdJSk8Un1",
+		"06483800 eret                   EMPTY",
+	))
+
+	testcases := []struct {
+		word     uint32
+		expected string
+	}{
+		{0x02ffd0a4, "addi.d $r4, $r5, -12"},
+		{0x260004a4, "ldox4.d $r4, $r5, 4"},
+		{0x002c9483, "sladd.d $r3, $r4, $r5, 2"},
+		{0x53ffffff, "b -4"},
+		{0x480000e0, "bceqz $fcc7, 0"},
+		{0x00000823, "movgr2scr $scr3, $r1"},
+		{0x0c100c47, "fcmp.caf.s $fcc7, $f2, $f3"},
+		{0x2c8004a4, "xvld $xr4, $r5, 1"},
+		{0x3117fc41, "vstelm.d $vr1, $r2, -1, 1"},
+		{0x06483800, "eret"},
+	}
+
+	for _, tc := range testcases {
+		actual, err := d.Disassemble(tc.word)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, actual)
+	}
+}
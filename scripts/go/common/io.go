@@ -1,13 +1,69 @@
 package common
 
+import (
+	"errors"
+	"path/filepath"
+)
+
+// ReadInsnDescs reads insn descriptions from all files in paths.
+//
+// Malformed lines of all files are reported together in the returned error,
+// which is of type ParseErrors in that case.
 func ReadInsnDescs(paths []string) ([]*InsnDescription, error) {
 	var result []*InsnDescription
+	var errs ParseErrors
 	for _, path := range paths {
 		descs, err := ReadInsnDescriptionFile(path)
 		if err != nil {
+			var pes ParseErrors
+			if errors.As(err, &pes) {
+				errs = append(errs, pes...)
+				continue
+			}
 			return nil, err
 		}
 		result = append(result, descs...)
 	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
 	return result, nil
 }
+
+// TablesDir is the directory containing the insn description files,
+// relative to the scripts/go directory the tools are run from.
+const TablesDir = "../.."
+
+// ReadAllInsnDescs reads insn descriptions from all files in dir.
+func ReadAllInsnDescs(dir string) ([]*InsnDescription, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadInsnDescs(paths)
+}
+
+// ReadSelectedInsnDescs reads insn descriptions from all files in paths, or
+// from all files in TablesDir if paths is empty, and returns the ones matched
+// by the selector expression, see ParseSelector.
+func ReadSelectedInsnDescs(paths []string, selector string) ([]*InsnDescription, error) {
+	sel, err := ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var descs []*InsnDescription
+	if len(paths) == 0 {
+		descs, err = ReadAllInsnDescs(TablesDir)
+	} else {
+		descs, err = ReadInsnDescs(paths)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return Select(descs, sel), nil
+}
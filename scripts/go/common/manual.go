@@ -0,0 +1,158 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/registers"
+)
+
+// ManualMnemonic returns the mnemonic of the insn in the manual syntax, which
+// is also the one accepted by binutils.
+func (d *InsnDescription) ManualMnemonic() string {
+	if d.Attribs.OrigName != "" {
+		return d.Attribs.OrigName
+	}
+	return d.Mnemonic
+}
+
+// ManualFormat returns the format of the insn in the manual syntax, with
+// args in manual order and carrying their postprocess ops.
+func (d *InsnDescription) ManualFormat() *InsnFormat {
+	if d.OrigFormat != nil {
+		return d.OrigFormat
+	}
+	return d.Format
+}
+
+// manualOrder returns, for every arg of the manual syntax, the index of the
+// canonical arg occupying the same slots. It returns nil if the args of the
+// two formats do not correspond one-to-one.
+//
+// Arg kinds are not compared, for the FCSR operands are unsigned immediates
+// in canonical syntax, but integer registers in the manual syntax.
+func (d *InsnDescription) manualOrder() []int {
+	manualArgs := d.ManualFormat().Args
+	if len(manualArgs) != len(d.Format.Args) {
+		return nil
+	}
+
+	result := make([]int, len(manualArgs))
+	for i, ma := range manualArgs {
+		result[i] = -1
+		for j, a := range d.Format.Args {
+			if a.Bitmask() == ma.Bitmask() {
+				result[i] = j
+				break
+			}
+		}
+
+		if result[i] == -1 {
+			return nil
+		}
+	}
+
+	return result
+}
+
+// manualRegClass returns the register class of the i-th arg of the manual
+// syntax, which must be a register arg.
+func (d *InsnDescription) manualRegClass(i int) registers.Class {
+	canonicalArg := d.Format.Args[d.manualOrder()[i]]
+	if canonicalArg.Kind.IsImm() {
+		return registers.ClassFCSR
+	}
+	return d.ManualFormat().Args[i].Kind.RegClass()
+}
+
+// ToManualOperands reorders operands given in canonical order into manual
+// order. Operand values are left untouched.
+func (d *InsnDescription) ToManualOperands(operands []int64) []int64 {
+	order := d.manualOrder()
+	result := make([]int64, len(order))
+	for i, j := range order {
+		result[i] = operands[j]
+	}
+	return result
+}
+
+// FromManualOperands reorders operands given in manual order into canonical
+// order. It is the inverse of ToManualOperands.
+func (d *InsnDescription) FromManualOperands(operands []int64) []int64 {
+	order := d.manualOrder()
+	result := make([]int64, len(order))
+	for i, j := range order {
+		result[j] = operands[i]
+	}
+	return result
+}
+
+// Translator converts insns between canonical and manual syntax.
+type Translator struct {
+	byMnemonic       map[string]*InsnDescription
+	byManualMnemonic map[string]*InsnDescription
+}
+
+func NewTranslator(descs []*InsnDescription) *Translator {
+	byMnemonic := make(map[string]*InsnDescription, len(descs))
+	byManualMnemonic := make(map[string]*InsnDescription, len(descs))
+	for _, d := range descs {
+		byMnemonic[d.Mnemonic] = d
+		byManualMnemonic[d.ManualMnemonic()] = d
+	}
+
+	return &Translator{
+		byMnemonic:       byMnemonic,
+		byManualMnemonic: byManualMnemonic,
+	}
+}
+
+// Lookup returns the insn with the given canonical mnemonic, or nil.
+func (t *Translator) Lookup(mnemonic string) *InsnDescription {
+	return t.byMnemonic[mnemonic]
+}
+
+// LookupManual returns the insn with the given manual mnemonic, or nil.
+func (t *Translator) LookupManual(mnemonic string) *InsnDescription {
+	return t.byManualMnemonic[mnemonic]
+}
+
+// ToManual translates an insn given by canonical mnemonic and operands in
+// canonical order into manual syntax.
+func (t *Translator) ToManual(mnemonic string, operands []int64) (string, []int64, error) {
+	d := t.Lookup(mnemonic)
+	if d == nil {
+		return "", nil, fmt.Errorf("unknown mnemonic %s", strconv.Quote(mnemonic))
+	}
+
+	if len(operands) != len(d.Format.Args) {
+		return "", nil, fmt.Errorf(
+			"%s: expected %d operand(s), got %d",
+			mnemonic,
+			len(d.Format.Args),
+			len(operands),
+		)
+	}
+
+	return d.ManualMnemonic(), d.ToManualOperands(operands), nil
+}
+
+// FromManual translates an insn given by manual mnemonic and operands in
+// manual order into canonical syntax.
+func (t *Translator) FromManual(mnemonic string, operands []int64) (string, []int64, error) {
+	d := t.LookupManual(mnemonic)
+	if d == nil {
+		return "", nil, fmt.Errorf("unknown mnemonic %s", strconv.Quote(mnemonic))
+	}
+
+	if len(operands) != len(d.Format.Args) {
+		return "", nil, fmt.Errorf(
+			"%s: expected %d operand(s), got %d",
+			mnemonic,
+			len(d.Format.Args),
+			len(operands),
+		)
+	}
+
+	return d.Mnemonic, d.FromManualOperands(operands), nil
+}
@@ -0,0 +1,96 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var manualTestLines = []string{
+	"00100000 add.w                  DJK",
+	"38590000 amcas.w                DJK             @orig_fmt=DKJ @rev=1p10",
+	"60000000 bgt                    DJSk16          @orig_name=blt @orig_fmt=JDSk16ps2",
+	"002c0000 sladd.d                DJKUa2          @orig_name=alsl.d @orig_fmt=DJKUa2pp1",
+	"0114c000 fcsrwr                 JUd5            @orig_name=movgr2fcsr @orig_fmt=DJ",
+	"0114c800 fcsrrd                 DUj5            @orig_name=movfcsr2gr @orig_fmt=DJ",
+	"06483800 eret                   EMPTY",
+}
+
+func TestTranslator(t *testing.T) {
+	tr := NewTranslator(mustParseInsnDescriptionLines(t, manualTestLines...))
+
+	testcases := []struct {
+		mnemonic       string
+		operands       []int64
+		manualMnemonic string
+		manualOperands []int64
+	}{
+		{"add.w", []int64{4, 5, 6}, "add.w", []int64{4, 5, 6}},
+		{"amcas.w", []int64{4, 5, 6}, "amcas.w", []int64{4, 6, 5}},
+		{"bgt", []int64{4, 5, 2}, "blt", []int64{5, 4, 2}},
+		{"sladd.d", []int64{3, 4, 5, 1}, "alsl.d", []int64{3, 4, 5, 1}},
+		{"fcsrwr", []int64{4, 1}, "movgr2fcsr", []int64{1, 4}},
+		{"fcsrrd", []int64{4, 1}, "movfcsr2gr", []int64{4, 1}},
+		{"eret", []int64{}, "eret", []int64{}},
+	}
+
+	for _, tc := range testcases {
+		m, ops, err := tr.ToManual(tc.mnemonic, tc.operands)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.manualMnemonic, m)
+		assert.Equal(t, tc.manualOperands, ops)
+
+		m, ops, err = tr.FromManual(tc.manualMnemonic, tc.manualOperands)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.mnemonic, m)
+		assert.Equal(t, tc.operands, ops)
+	}
+
+	assert.Nil(t, tr.LookupManual("bgt"))
+	assert.Nil(t, tr.Lookup("blt"))
+
+	_, _, err := tr.ToManual("blt", []int64{4, 5, 2})
+	assert.Error(t, err)
+	_, _, err = tr.FromManual("blt", []int64{4, 5})
+	assert.Error(t, err)
+}
+
+func TestManualSyntax(t *testing.T) {
+	descs := mustParseInsnDescriptionLines(t, manualTestLines...)
+	d := NewDecoder(descs)
+	a := NewManualAssembler(descs)
+
+	testcases := []struct {
+		word     uint32
+		expected string
+	}{
+		{0x00101483, "add.w $sp, $a0, $a1"},
+		{0x385918a4, "amcas.w $a0, $a2, $a1"},
+		{0x600008a4, "blt $a1, $a0, 8"},
+		{0x002c9483, "alsl.d $sp, $a0, $a1, 2"},
+		{0x0114c081, "movgr2fcsr $fcsr1, $a0"},
+		{0x0114c824, "movfcsr2gr $a0, $fcsr1"},
+		{0x06483800, "eret"},
+	}
+
+	for _, tc := range testcases {
+		actual, err := d.DisassembleManual(tc.word)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, actual)
+
+		word, err := a.Assemble(tc.expected)
+		assert.NoError(t, err, tc.expected)
+		assert.Equal(t, tc.word, word, tc.expected)
+	}
+
+	// canonical mnemonics and operand kinds are not accepted
+	for _, x := range []string{
+		"bgt $a0, $a1, 8",
+		"sladd.d $sp, $a0, $a1, 2",
+		"movgr2fcsr 1, $a0",
+		"movgr2fcsr $a1, $a0",
+	} {
+		_, err := a.Assemble(x)
+		assert.Error(t, err, x)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/registers"
 )
 
 type InsnDescription struct {
@@ -12,7 +14,13 @@ type InsnDescription struct {
 	Mnemonic   string
 	Format     *InsnFormat
 	OrigFormat *InsnFormat
-	Attribs    map[string]string
+	Attribs    InsnAttribs
+
+	// Source is the base name of the file the insn is described in, empty
+	// if not read from a file.
+	Source string
+	Ext    Extension
+	Width  Width
 }
 
 type InsnFormat struct {
@@ -56,6 +64,21 @@ func (k *PostprocessOp) CanonicalRepr() string {
 	}
 }
 
+// Apply transforms an encoded immediate value into the value presented in
+// the manual syntax.
+func (k *PostprocessOp) Apply(x int64) int64 {
+	switch k.Kind {
+	case PostprocessOpKindNone:
+		return x
+	case PostprocessOpKindAdd:
+		return x + int64(k.Amount)
+	case PostprocessOpKindShl:
+		return x << k.Amount
+	default:
+		panic("unreachable")
+	}
+}
+
 type ArgKind int
 
 const (
@@ -86,6 +109,26 @@ func (k ArgKind) Validate() error {
 	return fmt.Errorf("unknown arg kind: %d", k)
 }
 
+// RegClass returns the register class referred to by args of the kind.
+func (k ArgKind) RegClass() registers.Class {
+	switch k {
+	case ArgKindIntReg:
+		return registers.ClassGPR
+	case ArgKindFPReg:
+		return registers.ClassFPR
+	case ArgKindFCCReg:
+		return registers.ClassFCC
+	case ArgKindScratchReg:
+		return registers.ClassScratch
+	case ArgKindVReg:
+		return registers.ClassLSX
+	case ArgKindXReg:
+		return registers.ClassLASX
+	default:
+		return registers.ClassUnknown
+	}
+}
+
 func (k ArgKind) IsImm() bool {
 	switch k {
 	case ArgKindSignedImm, ArgKindUnsignedImm:
@@ -225,6 +268,102 @@ func (a *Arg) TotalWidth() uint {
 	return result
 }
 
+// SlotPart is one slot of an arg, along with the shift amount to apply to
+// the arg's value to get the slot's value, before masking with the slot's
+// width.
+type SlotPart struct {
+	Slot  *Slot
+	Shift uint
+}
+
+// SlotParts returns how the arg's value is split into its slots.
+//
+// Slots are concatenated from left (MSB) to right (LSB) to form the arg's
+// value. Take example of Sd5k16:
+//
+//	Sd5k16 = (MSB) DDDDDKKKKKKKKKKKKKKKK (LSB)
+//
+// Consuming the slots from left to right, with the shift amount initially
+// being 5+16:
+//
+//	slot d5:  shift = 16, thus d5 = (sd5k16 >> 16) & 0b11111
+//	slot k16: shift = 0,  thus k16 = sd5k16 & 0b1111111111111111
+func (a *Arg) SlotParts() []SlotPart {
+	result := make([]SlotPart, len(a.Slots))
+	remainingBits := a.TotalWidth()
+	for i, s := range a.Slots {
+		remainingBits -= s.Width
+		result[i] = SlotPart{
+			Slot:  s,
+			Shift: remainingBits,
+		}
+	}
+	return result
+}
+
+// Extract returns the arg's value as encoded in the given insn word.
+//
+// Signed immediates are sign-extended, then the postprocess op, if any, is
+// applied.
+func (a *Arg) Extract(word uint32) int64 {
+	var raw uint64
+	for _, s := range a.Slots {
+		slotVal := (word & s.Bitmask()) >> s.Offset
+		raw = raw<<s.Width | uint64(slotVal)
+	}
+
+	result := int64(raw)
+	if a.Kind == ArgKindSignedImm {
+		shamt := 64 - a.TotalWidth()
+		result = int64(raw<<shamt) >> shamt
+	}
+
+	return a.Post.Apply(result)
+}
+
+// ValueRange returns the range of values the arg can take, after
+// postprocessing.
+func (a *Arg) ValueRange() (min int64, max int64) {
+	width := a.TotalWidth()
+	if a.Kind == ArgKindSignedImm {
+		min = -(int64(1) << (width - 1))
+		max = (int64(1) << (width - 1)) - 1
+	} else {
+		min = 0
+		max = (int64(1) << width) - 1
+	}
+
+	return a.Post.Apply(min), a.Post.Apply(max)
+}
+
+// Encode returns the slot bits representing the given postprocessed value
+// of the arg. It is the inverse of Extract.
+func (a *Arg) Encode(val int64) (uint32, error) {
+	min, max := a.ValueRange()
+	if val < min || val > max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", val, min, max)
+	}
+
+	x := val
+	switch a.Post.Kind {
+	case PostprocessOpKindAdd:
+		x -= int64(a.Post.Amount)
+	case PostprocessOpKindShl:
+		if x&((int64(1)<<a.Post.Amount)-1) != 0 {
+			return 0, fmt.Errorf("value %d not a multiple of %d", val, 1<<a.Post.Amount)
+		}
+		x >>= a.Post.Amount
+	}
+
+	var result uint32
+	for _, p := range a.SlotParts() {
+		slotVal := uint32(x>>p.Shift) & ((uint32(1) << p.Slot.Width) - 1)
+		result |= slotVal << p.Slot.Offset
+	}
+
+	return result, nil
+}
+
 func (a *Arg) String() string {
 	if a == nil {
 		return "<nil Arg>"
@@ -376,6 +515,36 @@ func (f *InsnFormat) MatchBitmask() uint32 {
 	return ^f.ArgsBitmask()
 }
 
+// Encode fills the given operands into the respective args' slots of word.
+//
+// Operands are in the format's order, and are postprocessed values if the
+// args have postprocess ops; see Arg.Encode.
+func (f *InsnFormat) Encode(word uint32, operands []int64) (uint32, error) {
+	if len(operands) != len(f.Args) {
+		return 0, fmt.Errorf("expected %d operand(s), got %d", len(f.Args), len(operands))
+	}
+
+	for i, a := range f.Args {
+		bits, err := a.Encode(operands[i])
+		if err != nil {
+			return 0, fmt.Errorf("operand %d: %w", i+1, err)
+		}
+
+		word |= bits
+	}
+
+	return word, nil
+}
+
+// Decode extracts all operands from word, in the format's order.
+func (f *InsnFormat) Decode(word uint32) []int64 {
+	result := make([]int64, len(f.Args))
+	for i, a := range f.Args {
+		result[i] = a.Extract(word)
+	}
+	return result
+}
+
 func (d *InsnDescription) Validate() error {
 	if d.Mnemonic == "" {
 		return errors.New("empty mnemonic")
@@ -394,5 +563,123 @@ func (d *InsnDescription) Validate() error {
 		)
 	}
 
+	if d.OrigFormat != nil {
+		err := d.OrigFormat.ValidateManualSyntax()
+		if err != nil {
+			return err
+		}
+
+		if d.manualOrder() == nil {
+			return fmt.Errorf(
+				"manual syntax format %s has different args than %s",
+				d.OrigFormat.CanonicalRepr(),
+				d.Format.CanonicalRepr(),
+			)
+		}
+	}
+
 	return nil
 }
+
+// pcRelBranches are the PC-relative branch insns, by canonical mnemonic.
+//
+// They are listed explicitly, because their formats are shared by e.g.
+// jirl, whose offset is relative to a register instead.
+var pcRelBranches = map[string]bool{
+	"beqz":  true,
+	"bnez":  true,
+	"bceqz": true,
+	"bcnez": true,
+	"b":     true,
+	"bl":    true,
+	"beq":   true,
+	"bne":   true,
+	"bgt":   true,
+	"ble":   true,
+	"bgtu":  true,
+	"bleu":  true,
+}
+
+// BranchTargetIndex returns the index, in canonical order, of the arg
+// holding the target offset of a PC-relative branch insn, or -1 if the insn
+// is not one.
+//
+// The offset is the signed immediate shifted by the postprocess op of the
+// manual syntax.
+func (d *InsnDescription) BranchTargetIndex() int {
+	if !pcRelBranches[d.Mnemonic] {
+		return -1
+	}
+
+	for i, a := range d.PostprocessedFormat().Args {
+		if a.Kind == ArgKindSignedImm && a.Post.Kind == PostprocessOpKindShl {
+			return i
+		}
+	}
+	return -1
+}
+
+// Matches reports whether word is an encoding of the insn.
+func (d *InsnDescription) Matches(word uint32) bool {
+	return word&d.Format.MatchBitmask() == d.Word
+}
+
+// Encode returns the insn word with the given operands, in canonical order.
+//
+// Operands are postprocessed values as in the manual syntax, e.g. branch
+// offsets are in bytes; see PostprocessedFormat.
+func (d *InsnDescription) Encode(operands []int64) (uint32, error) {
+	return d.PostprocessedFormat().Encode(d.Word, operands)
+}
+
+// Decode extracts the operands of the insn from word, in canonical order.
+//
+// Operands are postprocessed values, like those taken by Encode.
+func (d *InsnDescription) Decode(word uint32) ([]int64, error) {
+	if !d.Matches(word) {
+		return nil, fmt.Errorf("%08x is not a %s insn", word, d.Mnemonic)
+	}
+
+	return d.PostprocessedFormat().Decode(word), nil
+}
+
+// PostprocessedFormat returns the canonical format of the insn, with every
+// arg carrying the postprocess op recorded for it in the manual syntax.
+//
+// Args are matched to their manual syntax counterparts by their slots.
+func (d *InsnDescription) PostprocessedFormat() *InsnFormat {
+	if d.OrigFormat == nil {
+		return d.Format
+	}
+
+	args := make([]*Arg, len(d.Format.Args))
+	for i, a := range d.Format.Args {
+		args[i] = a
+
+		mask := a.Bitmask()
+		for _, oa := range d.OrigFormat.Args {
+			if oa.Bitmask() != mask || oa.Post.Kind == PostprocessOpKindNone {
+				continue
+			}
+
+			args[i] = &Arg{
+				Kind:  a.Kind,
+				Slots: a.Slots,
+				Post:  oa.Post,
+			}
+			break
+		}
+	}
+
+	return &InsnFormat{
+		Args: args,
+	}
+}
+
+// Overlaps reports whether there exists an insn word matching both d and
+// other, i.e. whether the two insns cannot be told apart by a decoder in
+// all cases.
+func (d *InsnDescription) Overlaps(other *InsnDescription) bool {
+	commonMask := d.Format.MatchBitmask() & other.Format.MatchBitmask()
+	return (d.Word^other.Word)&commonMask == 0
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -211,3 +212,135 @@ func TestInsnFormat(t *testing.T) {
 		assert.Equal(t, &tc.x, roundtrip, "canonical repr should survive round-trip")
 	}
 }
+
+func TestArgEncodeExtract(t *testing.T) {
+	testcases := []struct {
+		argRepr     string
+		val         int64
+		ok          bool
+		expectedEnc uint32
+	}{
+		{argRepr: "D", val: 31, ok: true, expectedEnc: 0x1f},
+		{argRepr: "Fk", val: 3, ok: true, expectedEnc: 3 << 10},
+		{argRepr: "D", val: 32},
+		{argRepr: "Uk12", val: 0xfff, ok: true, expectedEnc: 0xfff << 10},
+		{argRepr: "Uk12", val: 0x1000},
+		{argRepr: "Uk12", val: -1},
+		{argRepr: "Sk12", val: -1, ok: true, expectedEnc: 0xfff << 10},
+		{argRepr: "Sk12", val: -2048, ok: true, expectedEnc: 0x800 << 10},
+		{argRepr: "Sk12", val: 2047, ok: true, expectedEnc: 0x7ff << 10},
+		{argRepr: "Sk12", val: 2048},
+		{argRepr: "Sk12", val: -2049},
+		// the d5 slot holds the high bits
+		{argRepr: "Sd5k16", val: 0x10000, ok: true, expectedEnc: 0x1},
+		{argRepr: "Sd5k16", val: 0xffff, ok: true, expectedEnc: 0xffff << 10},
+		{argRepr: "Sd5k16", val: -0x100000, ok: true, expectedEnc: 0x10},
+		{argRepr: "Sd10k16ps2", val: -4, ok: true, expectedEnc: 0x3ffffff},
+		{argRepr: "Sd10k16ps2", val: 0x4, ok: true, expectedEnc: 0x1 << 10},
+		{argRepr: "Sd10k16ps2", val: 0x40000, ok: true, expectedEnc: 0x1},
+		{argRepr: "Sd10k16ps2", val: 2},
+		{argRepr: "Sd10k16ps2", val: 1 << 27},
+		{argRepr: "Ua2pp1", val: 1, ok: true, expectedEnc: 0},
+		{argRepr: "Ua2pp1", val: 4, ok: true, expectedEnc: 3 << 15},
+		{argRepr: "Ua2pp1", val: 0},
+		{argRepr: "Ua2pp1", val: 5},
+	}
+
+	for _, tc := range testcases {
+		f, err := ParseInsnFormat(tc.argRepr)
+		assert.NoError(t, err)
+		a := f.Args[0]
+
+		enc, err := a.Encode(tc.val)
+		if !tc.ok {
+			assert.Error(t, err, "%s: %d", tc.argRepr, tc.val)
+			continue
+		}
+
+		assert.NoError(t, err, "%s: %d", tc.argRepr, tc.val)
+		assert.Equal(t, tc.expectedEnc, enc, "%s: %d", tc.argRepr, tc.val)
+		assert.Equal(t, tc.val, a.Extract(enc|^a.Bitmask()), "%s: %d", tc.argRepr, tc.val)
+	}
+}
+
+func TestInsnDescriptionEncodeDecode(t *testing.T) {
+	d, err := ParseInsnDescriptionLine("40000000 beqz                   JSd5k16         @orig_fmt=JSd5k16ps2")
+	assert.NoError(t, err)
+
+	// the offset is in bytes, as per the ps2 in the manual syntax
+	word, err := d.Encode([]int64{4, -0x400000 + 16})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0x40001090), word)
+
+	operands, err := d.Decode(word)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{4, -0x400000 + 16}, operands)
+
+	_, err = d.Encode([]int64{4})
+	assert.Error(t, err)
+
+	_, err = d.Encode([]int64{4, 2})
+	assert.Error(t, err)
+
+	_, err = d.Decode(0x44001090)
+	assert.Error(t, err)
+}
+
+func TestInsnDescriptionOverlaps(t *testing.T) {
+	descs := mustParseInsnDescriptionLines(
+		t,
+		"00100000 add.w                  DJK",
+		"00108000 add.d                  DJK",
+		"00100000 foo                    DJ",
+		"00000800 movgr2scr              TdJ",
+		"00000000 bar                    Uk15",
+		"02000000 baz                    Uk15",
+	)
+
+	testcases := []struct {
+		a, b     int
+		expected bool
+	}{
+		{0, 0, true},
+		{0, 1, false},
+		{0, 2, true},
+		{1, 2, false},
+		{3, 4, true},
+		{0, 4, true},
+		{0, 5, false},
+		{4, 5, false},
+	}
+
+	for _, tc := range testcases {
+		a, b := descs[tc.a], descs[tc.b]
+		assert.Equal(t, tc.expected, a.Overlaps(b), "%s vs %s", a.Mnemonic, b.Mnemonic)
+		assert.Equal(t, tc.expected, b.Overlaps(a), "%s vs %s", b.Mnemonic, a.Mnemonic)
+	}
+}
+
+func TestBranchTargetIndexRealTables(t *testing.T) {
+	// tests are run in the package directory, one level below the commands
+	descs, err := ReadAllInsnDescs(filepath.Join("..", TablesDir))
+	assert.NoError(t, err)
+
+	found := make(map[string]bool)
+	for _, d := range descs {
+		i := d.BranchTargetIndex()
+		if !pcRelBranches[d.Mnemonic] {
+			assert.Equal(t, -1, i, d.Mnemonic)
+			continue
+		}
+
+		found[d.Mnemonic] = true
+		if assert.GreaterOrEqual(t, i, 0, d.Mnemonic) {
+			a := d.PostprocessedFormat().Args[i]
+			assert.Equal(t, PostprocessOpKindShl, a.Post.Kind, d.Mnemonic)
+			assert.Equal(t, 2, a.Post.Amount, d.Mnemonic)
+		}
+	}
+
+	// every listed branch is in the tables
+	for m := range pcRelBranches {
+		assert.True(t, found[m], m)
+	}
+}
@@ -8,21 +8,123 @@ import (
 	"strings"
 )
 
-var insnRE = regexp.MustCompile(`^([0-9a-f]{8}) ([a-z][0-9a-z_.]*) +(EMPTY|[0-9DJKACFVXSTUdjkamn]+)((?: *@[0-9A-Za-z_.=]+)*)$`)
-var attribRE = regexp.MustCompile(`@[0-9A-Za-z_.]+(?:=[0-9A-Za-z_.]*)?`)
+var insnRE = regexp.MustCompile(`^([0-9a-f]{8}) ([a-z][0-9a-z_.]*) +([0-9A-Za-z]+)((?: *@[0-9A-Za-z_.=]+)*)$`)
+var attribRE = regexp.MustCompile(`@([0-9A-Za-z_.]+)(?:=([0-9A-Za-z_.]*))?`)
+
+// used for pinpointing the malformed part of a line not matching insnRE
+var insnWordPrefixRE = regexp.MustCompile(`^[0-9a-f]{8} `)
+var insnMnemonicPrefixRE = regexp.MustCompile(`^[0-9a-f]{8} [a-z][0-9a-z_.]*(?: +|$)`)
+var insnFormatPrefixRE = regexp.MustCompile(`^[0-9a-f]{8} [a-z][0-9a-z_.]* +[0-9A-Za-z]+`)
 
 const origFmtKey = "orig_fmt"
 
+// ParseError is an error found in an insn description line.
+type ParseError struct {
+	// Path is the path of the file containing the line, if known.
+	Path string
+	// Line is the 1-based line number, or 0 if not known.
+	Line int
+	// Column is the 1-based byte column of the offending part of the line,
+	// or 0 if not known.
+	Column int
+	// Text is the offending line.
+	Text string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	var sb strings.Builder
+
+	if e.Path != "" {
+		sb.WriteString(e.Path)
+		sb.WriteRune(':')
+	}
+
+	if e.Line > 0 {
+		sb.WriteString(strconv.Itoa(e.Line))
+		sb.WriteRune(':')
+
+		if e.Column > 0 {
+			sb.WriteString(strconv.Itoa(e.Column))
+			sb.WriteRune(':')
+		}
+	} else if e.Column > 0 {
+		sb.WriteString("column ")
+		sb.WriteString(strconv.Itoa(e.Column))
+		sb.WriteRune(':')
+	}
+
+	if sb.Len() > 0 {
+		sb.WriteRune(' ')
+	}
+
+	sb.WriteString(e.Err.Error())
+	return sb.String()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseErrors is a list of ParseError's, in the order they are found.
+type ParseErrors []*ParseError
+
+func (l ParseErrors) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// FormatError is an error found in an insn format string.
+type FormatError struct {
+	// Offset is the 0-based offset of the offending character in the
+	// format string.
+	Offset int
+	Err    error
+}
+
+func (e *FormatError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FormatError) Unwrap() error {
+	return e.Err
+}
+
+// ParseInsnDescriptionLine parses one line of an insn description file.
+//
+// Errors returned are always of type *ParseError, with Path and Line left
+// for the caller to fill in.
 func ParseInsnDescriptionLine(line string) (*InsnDescription, error) {
-	matches := insnRE.FindStringSubmatch(line)
+	makeErr := func(offset int, err error) error {
+		return &ParseError{
+			Column: offset + 1,
+			Text:   line,
+			Err:    err,
+		}
+	}
+
+	matches := insnRE.FindStringSubmatchIndex(line)
 	if matches == nil {
-		return nil, errors.New("malformed insn description line")
+		offset, err := diagnoseMalformedLine(line)
+		return nil, makeErr(offset, err)
 	}
 
-	wordStr := matches[1]
-	mnemonic := matches[2]
-	insnFmtStr := matches[3]
-	attribsStr := matches[4]
+	wordStr := line[matches[2]:matches[3]]
+	mnemonic := line[matches[4]:matches[5]]
+	insnFmtOffset := matches[6]
+	insnFmtStr := line[insnFmtOffset:matches[7]]
+	attribsOffset := matches[8]
+	attribsStr := line[attribsOffset:matches[9]]
 
 	word64, err := strconv.ParseUint(wordStr, 16, 32)
 	if err != nil {
@@ -32,21 +134,35 @@ func ParseInsnDescriptionLine(line string) (*InsnDescription, error) {
 
 	insnFmt, err := ParseInsnFormat(insnFmtStr)
 	if err != nil {
-		return nil, err
+		return nil, makeErr(insnFmtOffset+formatErrorOffset(err), err)
+	}
+
+	for i, a := range insnFmt.Args {
+		if a.Post.Kind != PostprocessOpKindNone {
+			return nil, makeErr(
+				insnFmtOffset,
+				fmt.Errorf("postprocess op not allowed in canonical format: arg %d", i+1),
+			)
+		}
 	}
 
 	attribs, err := parseInsnAttribs(attribsStr)
 	if err != nil {
-		return nil, err
+		offset := attribsOffset
+		var ae *attribError
+		if errors.As(err, &ae) {
+			offset += ae.offset
+		}
+		return nil, makeErr(offset, err)
 	}
 
 	var origFmt *InsnFormat
-	if origFmtStr, ok := attribs[origFmtKey]; ok {
-		origFmt, err = ParseInsnFormat(origFmtStr)
+	if attribs.origFmt != "" {
+		origFmt, err = ParseInsnFormat(attribs.origFmt)
 		if err != nil {
-			return nil, err
+			offset := attribsOffset + attribs.origFmtOffset
+			return nil, makeErr(offset+formatErrorOffset(err), err)
 		}
-		delete(attribs, origFmtKey)
 	}
 
 	result := InsnDescription{
@@ -54,39 +170,48 @@ func ParseInsnDescriptionLine(line string) (*InsnDescription, error) {
 		Mnemonic:   mnemonic,
 		Format:     insnFmt,
 		OrigFormat: origFmt,
-		Attribs:    attribs,
+		Attribs:    attribs.attribs,
 	}
 
 	err = result.Validate()
 	if err != nil {
-		return nil, err
+		return nil, makeErr(insnFmtOffset, err)
 	}
 
 	return &result, nil
 }
 
-func parseInsnAttribs(input string) (map[string]string, error) {
-	matches := attribRE.FindAllString(input, -1)
-	if matches == nil {
-		return map[string]string{}, nil
+// diagnoseMalformedLine returns the offset of the first malformed part of a
+// line not matching insnRE, and the reason why.
+func diagnoseMalformedLine(line string) (int, error) {
+	if !insnWordPrefixRE.MatchString(line) {
+		return 0, errors.New("malformed insn word")
 	}
 
-	result := make(map[string]string, len(matches))
-	for _, s := range matches {
-		sepIdx := strings.Index(s, "=")
-		if sepIdx != -1 {
-			// @key=value form
-			result[s[1:sepIdx]] = s[sepIdx+1:]
-			continue
-		}
+	if !insnMnemonicPrefixRE.MatchString(line) {
+		return 9, errors.New("malformed insn mnemonic")
+	}
 
-		// @key form
-		result[s[1:]] = "true"
+	loc := insnFormatPrefixRE.FindStringIndex(line)
+	if loc == nil {
+		return len(insnMnemonicPrefixRE.FindString(line)), errors.New("malformed insn format")
 	}
 
-	return result, nil
+	return loc[1], errors.New("malformed insn attribs")
+}
+
+// formatErrorOffset returns the offset within the format string where the
+// error occurred, or 0 if not known.
+func formatErrorOffset(err error) int {
+	var fe *FormatError
+	if errors.As(err, &fe) {
+		return fe.Offset
+	}
+	return 0
 }
 
+// ParseInsnFormat parses an insn format string, either canonical or in the
+// manual syntax. Syntax errors returned are of type *FormatError.
 func ParseInsnFormat(input string) (*InsnFormat, error) {
 	// special-case "EMPTY"
 	if input == "EMPTY" {
@@ -125,6 +250,13 @@ type insnFormatLexer struct {
 	curr int
 }
 
+func (l *insnFormatLexer) errorAt(offset int, err error) error {
+	return &FormatError{
+		Offset: offset,
+		Err:    err,
+	}
+}
+
 func (l *insnFormatLexer) eof() bool {
 	return l.curr >= len(l.input)
 }
@@ -144,6 +276,7 @@ func (l *insnFormatLexer) peek() (next rune, wouldEOF bool) {
 
 func (l *insnFormatLexer) consumeArg() (*Arg, error) {
 	// EOF is checked outside (in ParseInsnFormat)
+	prefixPos := l.curr
 	prefixCh := l.eat()
 
 	switch prefixCh {
@@ -157,8 +290,7 @@ func (l *insnFormatLexer) consumeArg() (*Arg, error) {
 		return makeRegArg(15, ArgKindIntReg), nil
 
 	case 'C':
-		offsetCh := l.eat()
-		offset, err := parseOffsetCh(offsetCh)
+		offset, err := l.consumeOffsetCh()
 		if err != nil {
 			return nil, err
 		}
@@ -166,8 +298,7 @@ func (l *insnFormatLexer) consumeArg() (*Arg, error) {
 		return makeFCCRegArg(offset), nil
 
 	case 'F':
-		offsetCh := l.eat()
-		offset, err := parseOffsetCh(offsetCh)
+		offset, err := l.consumeOffsetCh()
 		if err != nil {
 			return nil, err
 		}
@@ -175,8 +306,7 @@ func (l *insnFormatLexer) consumeArg() (*Arg, error) {
 		return makeRegArg(offset, ArgKindFPReg), nil
 
 	case 'T':
-		offsetCh := l.eat()
-		offset, err := parseOffsetCh(offsetCh)
+		offset, err := l.consumeOffsetCh()
 		if err != nil {
 			return nil, err
 		}
@@ -184,8 +314,7 @@ func (l *insnFormatLexer) consumeArg() (*Arg, error) {
 		return makeScratchRegArg(offset), nil
 
 	case 'V':
-		offsetCh := l.eat()
-		offset, err := parseOffsetCh(offsetCh)
+		offset, err := l.consumeOffsetCh()
 		if err != nil {
 			return nil, err
 		}
@@ -193,8 +322,7 @@ func (l *insnFormatLexer) consumeArg() (*Arg, error) {
 		return makeRegArg(offset, ArgKindVReg), nil
 
 	case 'X':
-		offsetCh := l.eat()
-		offset, err := parseOffsetCh(offsetCh)
+		offset, err := l.consumeOffsetCh()
 		if err != nil {
 			return nil, err
 		}
@@ -226,7 +354,26 @@ func (l *insnFormatLexer) consumeArg() (*Arg, error) {
 		}, nil
 	}
 
-	return nil, fmt.Errorf("invalid prefix char %s", strconv.QuoteRune(prefixCh))
+	return nil, l.errorAt(
+		prefixPos,
+		fmt.Errorf("invalid prefix char %s", strconv.QuoteRune(prefixCh)),
+	)
+}
+
+func (l *insnFormatLexer) consumeOffsetCh() (uint, error) {
+	pos := l.curr
+	ch, wouldEOF := l.peek()
+	if wouldEOF {
+		return 0, l.errorAt(pos, errors.New("unexpected end of format, expecting offset char"))
+	}
+	_ = l.eat()
+
+	offset, err := parseOffsetCh(ch)
+	if err != nil {
+		return 0, l.errorAt(pos, err)
+	}
+
+	return offset, nil
 }
 
 func (l *insnFormatLexer) consumeAtLeastOneSlot() ([]*Slot, error) {
@@ -251,20 +398,22 @@ func (l *insnFormatLexer) consumeAtLeastOneSlot() ([]*Slot, error) {
 	}
 
 	if len(result) == 0 {
-		return nil, errors.New("no slot was consumed")
+		return nil, l.errorAt(l.curr, errors.New("no slot was consumed"))
 	}
 
 	return result, nil
 }
 
 func (l *insnFormatLexer) consumeSlot() (*Slot, error) {
-	offsetCh := l.eat()
-	offset, err := parseOffsetCh(offsetCh)
+	offset, err := l.consumeOffsetCh()
 	if err != nil {
 		return nil, err
 	}
 
-	width := l.consumeUint()
+	width, err := l.consumeUint()
+	if err != nil {
+		return nil, err
+	}
 
 	return &Slot{
 		Offset: offset,
@@ -272,8 +421,12 @@ func (l *insnFormatLexer) consumeSlot() (*Slot, error) {
 	}, nil
 }
 
-func (l *insnFormatLexer) consumeUint() uint {
-	firstCh := l.eat()
+func (l *insnFormatLexer) consumeUint() (uint, error) {
+	firstCh, wouldEOF := l.peek()
+	if wouldEOF || firstCh < '0' || firstCh > '9' {
+		return 0, l.errorAt(l.curr, errors.New("expecting digit"))
+	}
+	_ = l.eat()
 	result := uint(firstCh - '0')
 
 	for {
@@ -290,7 +443,7 @@ func (l *insnFormatLexer) consumeUint() uint {
 		result = 10*result + uint(nextCh-'0')
 	}
 
-	return result
+	return result, nil
 }
 
 func (l *insnFormatLexer) maybeConsumePostprocessOp() (PostprocessOp, error) {
@@ -301,13 +454,22 @@ func (l *insnFormatLexer) maybeConsumePostprocessOp() (PostprocessOp, error) {
 	_ = l.eat()
 
 	// "p" / "s"
-	ch = l.eat()
+	pos := l.curr
+	ch, wouldEOF = l.peek()
+	if wouldEOF {
+		return PostprocessOp{}, l.errorAt(pos, errors.New("unexpected end of format, expecting postprocess op kind char"))
+	}
+	_ = l.eat()
+
 	kind, err := parsePostprocessOpKindCh(ch)
 	if err != nil {
-		return PostprocessOp{}, err
+		return PostprocessOp{}, l.errorAt(pos, err)
 	}
 
-	amt := l.consumeUint()
+	amt, err := l.consumeUint()
+	if err != nil {
+		return PostprocessOp{}, err
+	}
 
 	return PostprocessOp{
 		Kind:   kind,
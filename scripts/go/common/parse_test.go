@@ -21,7 +21,6 @@ func TestParseInsnDescriptionLine(t *testing.T) {
 				Format: &InsnFormat{
 					Args: nil,
 				},
-				Attribs: map[string]string{},
 			},
 		},
 		{
@@ -37,7 +36,6 @@ func TestParseInsnDescriptionLine(t *testing.T) {
 						{Kind: ArgKindSignedImm, Slots: []*Slot{{Offset: 10, Width: 14}}},
 					},
 				},
-				Attribs: map[string]string{},
 			},
 		},
 		{
@@ -53,7 +51,6 @@ func TestParseInsnDescriptionLine(t *testing.T) {
 						{Kind: ArgKindSignedImm, Slots: []*Slot{{Offset: 10, Width: 12}}},
 					},
 				},
-				Attribs: map[string]string{},
 			},
 		},
 		{
@@ -71,11 +68,10 @@ func TestParseInsnDescriptionLine(t *testing.T) {
 						}},
 					},
 				},
-				Attribs: map[string]string{},
 			},
 		},
 		{
-			x:  "20000000 ll.w                   DJSk14     @orig_fmt=DJSk14ps2 @32 @atomics @primary @foo=amswap_db.d",
+			x:  "20000000 ll.w                   DJSk14     @orig_fmt=DJSk14ps2 @la32 @primary @qemu @rev=1p10",
 			ok: true,
 			expected: &InsnDescription{
 				Word:     0x20000000,
@@ -94,11 +90,11 @@ func TestParseInsnDescriptionLine(t *testing.T) {
 						{Kind: ArgKindSignedImm, Slots: []*Slot{{Offset: 10, Width: 14}}, Post: PostprocessOp{Kind: PostprocessOpKindShl, Amount: 2}},
 					},
 				},
-				Attribs: map[string]string{
-					"32":      "true",
-					"atomics": "true",
-					"primary": "true",
-					"foo":     "amswap_db.d",
+				Attribs: InsnAttribs{
+					LA32:    true,
+					Primary: true,
+					QEMU:    true,
+					Rev:     ISARevision{Major: 1, Minor: 10},
 				},
 			},
 		},
@@ -124,8 +120,8 @@ func TestParseInsnDescriptionLine(t *testing.T) {
 						{Kind: ArgKindUnsignedImm, Slots: []*Slot{{Offset: 15, Width: 2}}, Post: PostprocessOp{Kind: PostprocessOpKindAdd, Amount: 1}},
 					},
 				},
-				Attribs: map[string]string{
-					"orig_name": "alsl.d",
+				Attribs: InsnAttribs{
+					OrigName: "alsl.d",
 				},
 			},
 		},
@@ -143,7 +139,6 @@ func TestParseInsnDescriptionLine(t *testing.T) {
 						{Kind: ArgKindUnsignedImm, Slots: []*Slot{{Offset: 18, Width: 1}}},
 					},
 				},
-				Attribs: map[string]string{},
 			},
 		},
 		{
@@ -159,7 +154,6 @@ func TestParseInsnDescriptionLine(t *testing.T) {
 						{Kind: ArgKindSignedImm, Slots: []*Slot{{Offset: 10, Width: 12}}},
 					},
 				},
-				Attribs: map[string]string{},
 			},
 		},
 	}
@@ -175,3 +169,49 @@ func TestParseInsnDescriptionLine(t *testing.T) {
 		}
 	}
 }
+
+func TestParseInsnDescriptionLineErrors(t *testing.T) {
+	testcases := []struct {
+		x              string
+		expectedColumn int
+	}{
+		{x: "1234567 foo EMPTY", expectedColumn: 1},
+		{x: "12345678 Foo EMPTY", expectedColumn: 10},
+		{x: "12345678 foo", expectedColumn: 13},
+		{x: "12345678 foo                   DJ, @qemu", expectedColumn: 34},
+		{x: "12345678 foo                   DJQ", expectedColumn: 34},
+		{x: "12345678 foo                   VdVq", expectedColumn: 35},
+		{x: "12345678 foo                   DJC", expectedColumn: 35},
+		{x: "12345678 foo                   DJSk", expectedColumn: 36},
+		{x: "12345678 foo                   DJS12", expectedColumn: 35},
+		{x: "12345678 foo                   DJSk14ps2", expectedColumn: 32},
+		{x: "12345678 foo                   DJSk14 @orig_fmt=DJSk14pq2", expectedColumn: 56},
+		// bad attribs
+		{x: "12345678 foo                   DJ @qmeu", expectedColumn: 35},
+		{x: "12345678 foo                   DJ @lbt @32", expectedColumn: 40},
+		{x: "12345678 foo                   DJ @qemu=true", expectedColumn: 35},
+		{x: "12345678 foo                   DJ @qemu @qemu", expectedColumn: 41},
+		{x: "12345678 foo                   DJ @orig_name", expectedColumn: 35},
+		{x: "12345678 foo                   DJ @orig_name=", expectedColumn: 35},
+		{x: "12345678 foo                   DJ @rev=1.10", expectedColumn: 40},
+		{x: "12345678 foo                   DJ @rev=1p", expectedColumn: 40},
+		{x: "12345678 foo                   DJ @rev=0p00", expectedColumn: 40},
+		// validation failures are reported at the start of the format
+		{x: "12345678 foo                   DJJ", expectedColumn: 32},
+		{x: "12345679 foo                   DJ", expectedColumn: 32},
+		{x: "12345678 foo                   DJK @orig_fmt=DJ", expectedColumn: 32},
+		{x: "12345678 foo                   DJK @orig_fmt=DJA", expectedColumn: 32},
+		{x: "12345678 foo                   DJK @orig_fmt=DJJ", expectedColumn: 32},
+	}
+
+	for _, tc := range testcases {
+		actual, err := ParseInsnDescriptionLine(tc.x)
+		assert.Nil(t, actual)
+
+		var pe *ParseError
+		if assert.ErrorAs(t, err, &pe, tc.x) {
+			assert.Equal(t, tc.expectedColumn, pe.Column, tc.x)
+			assert.Equal(t, tc.x, pe.Text)
+		}
+	}
+}
@@ -2,9 +2,19 @@ package common
 
 import (
 	"bufio"
+	"errors"
 	"os"
+	"path/filepath"
 )
 
+// ReadInsnDescriptionFile reads all insn descriptions from the file at path.
+//
+// The descriptions are tagged with the extension and width according to the
+// file name, see SubsetForPath. Unrecognized file names are tolerated, in
+// which case the insns have ExtUnknown and WidthUnknown.
+//
+// If any line is malformed, all such lines are reported in the returned
+// error, which is of type ParseErrors.
 func ReadInsnDescriptionFile(path string) ([]*InsnDescription, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -12,10 +22,17 @@ func ReadInsnDescriptionFile(path string) ([]*InsnDescription, error) {
 	}
 	defer f.Close()
 
+	// unrecognized names leave the insns untagged
+	ext, width, _ := SubsetForPath(path)
+	source := filepath.Base(path)
+
 	var result []*InsnDescription
+	var errs ParseErrors
 
 	sc := bufio.NewScanner(f)
+	lineNum := 0
 	for sc.Scan() {
+		lineNum++
 		l := sc.Text()
 
 		// the line read has no newline suffix, ready for consumption
@@ -27,11 +44,30 @@ func ReadInsnDescriptionFile(path string) ([]*InsnDescription, error) {
 
 		desc, err := ParseInsnDescriptionLine(l)
 		if err != nil {
-			return nil, err
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				pe = &ParseError{Text: l, Err: err}
+			}
+			pe.Path = path
+			pe.Line = lineNum
+			errs = append(errs, pe)
+			continue
 		}
 
+		desc.Source = source
+		desc.Ext = ext
+		desc.Width = width
+
 		result = append(result, desc)
 	}
 
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
 	return result, nil
 }
@@ -0,0 +1,40 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadInsnDescriptionFileErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo.txt")
+	content := "00100000 add.w                  DJK\n" +
+		"00110000 sub.w                  DJQ\n" +
+		"\n" +
+		"00120000 slt                    DJK\n" +
+		"0013000 maskeqz                DJK\n"
+	err := os.WriteFile(path, []byte(content), 0644)
+	assert.NoError(t, err)
+
+	descs, err := ReadInsnDescriptionFile(path)
+	assert.Nil(t, descs)
+
+	var errs ParseErrors
+	if assert.ErrorAs(t, err, &errs) && assert.Len(t, errs, 2) {
+		assert.Equal(t, path, errs[0].Path)
+		assert.Equal(t, 2, errs[0].Line)
+		assert.Equal(t, 35, errs[0].Column)
+		assert.Equal(t, "00110000 sub.w                  DJQ", errs[0].Text)
+
+		assert.Equal(t, 5, errs[1].Line)
+		assert.Equal(t, 1, errs[1].Column)
+	}
+
+	assert.Equal(
+		t,
+		path+":2:35: invalid prefix char 'Q'\n"+path+":5:1: malformed insn word",
+		err.Error(),
+	)
+}
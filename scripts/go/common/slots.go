@@ -0,0 +1,87 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GatherFormats returns the distinct formats of the insns, sorted by their
+// canonical repr.
+func GatherFormats(descs []*InsnDescription) []*InsnFormat {
+	formatsSet := make(map[string]*InsnFormat)
+	for _, d := range descs {
+		canonicalFormatName := d.Format.CanonicalRepr()
+		if _, ok := formatsSet[canonicalFormatName]; !ok {
+			formatsSet[canonicalFormatName] = d.Format
+		}
+	}
+
+	result := make([]*InsnFormat, 0, len(formatsSet))
+	for _, f := range formatsSet {
+		result = append(result, f)
+	}
+	sort.Slice(result, func(i int, j int) bool {
+		return result[i].CanonicalRepr() < result[j].CanonicalRepr()
+	})
+
+	return result
+}
+
+// SlotCombination returns the upper-cased offset chars of all slots of the
+// format, sorted by offset, e.g. "DJKM" for DJUk5Um5. It is empty for EMPTY.
+//
+// Formats sharing a slot combination can share the code putting the slot
+// values in place.
+func (f *InsnFormat) SlotCombination() string {
+	var offsets []int
+	for _, a := range f.Args {
+		for _, s := range a.Slots {
+			offsets = append(offsets, int(s.Offset))
+		}
+	}
+	sort.Ints(offsets)
+
+	var sb strings.Builder
+	for _, o := range offsets {
+		sb.WriteRune(unicode.ToUpper(rune(offsetCharsLower[o])))
+	}
+
+	return sb.String()
+}
+
+// GatherSlotCombinations returns the distinct slot combinations of the
+// formats, sorted, and skipping that of EMPTY.
+func GatherSlotCombinations(fmts []*InsnFormat) []string {
+	slotCombinationsSet := make(map[string]struct{})
+	for _, f := range fmts {
+		// skip EMPTY
+		if len(f.Args) == 0 {
+			continue
+		}
+		slotCombinationsSet[f.SlotCombination()] = struct{}{}
+	}
+
+	result := make([]string, 0, len(slotCombinationsSet))
+	for sc := range slotCombinationsSet {
+		result = append(result, sc)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// SlotOffsetForChar returns the slot offset denoted by the offset char of
+// either case, e.g. 5 for 'J' or 'j'.
+//
+// The char is expected to come from a slot combination, so it panics on
+// invalid chars.
+func SlotOffsetForChar(ch rune) uint {
+	offset, err := parseOffsetCh(unicode.ToLower(ch))
+	if err != nil {
+		panic(fmt.Sprintf("bad slot combination char %s", strconv.QuoteRune(ch)))
+	}
+	return offset
+}
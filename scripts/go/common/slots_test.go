@@ -0,0 +1,65 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGatherFormats(t *testing.T) {
+	descs := mustParseInsnDescriptionLines(
+		t,
+		"00100000 add.w                  DJK",
+		"02c00000 addi.d                 DJSk12",
+		"00108000 add.d                  DJK",
+		"06483800 eret                   EMPTY",
+	)
+
+	var reprs []string
+	for _, f := range GatherFormats(descs) {
+		reprs = append(reprs, f.CanonicalRepr())
+	}
+	assert.Equal(t, []string{"DJK", "DJSk12", "EMPTY"}, reprs)
+}
+
+func TestSlotCombination(t *testing.T) {
+	testcases := []struct {
+		fmt      string
+		expected string
+	}{
+		{"EMPTY", ""},
+		{"DJK", "DJK"},
+		{"JSd5k16", "DJK"},
+		{"DJUk5Um5", "DJKM"},
+		{"VdJSk8Un1", "DJKN"},
+		{"Sd10k16", "DK"},
+		{"FdFjFkFa", "DJKA"},
+	}
+
+	for _, tc := range testcases {
+		f, err := ParseInsnFormat(tc.fmt)
+		assert.NoError(t, err, tc.fmt)
+		assert.Equal(t, tc.expected, f.SlotCombination(), tc.fmt)
+	}
+}
+
+func TestGatherSlotCombinations(t *testing.T) {
+	var fmts []*InsnFormat
+	for _, s := range []string{"EMPTY", "DJK", "FdFjFk", "JSd5k16", "DJSk12", "Sd10k16"} {
+		f, err := ParseInsnFormat(s)
+		assert.NoError(t, err, s)
+		fmts = append(fmts, f)
+	}
+
+	assert.Equal(t, []string{"DJK", "DK"}, GatherSlotCombinations(fmts))
+}
+
+func TestSlotOffsetForChar(t *testing.T) {
+	for i, ch := range "DJKAMN" {
+		expected := []uint{0, 5, 10, 15, 16, 18}[i]
+		assert.Equal(t, expected, SlotOffsetForChar(ch))
+		assert.Equal(t, expected, SlotOffsetForChar(ch+'a'-'A'))
+	}
+
+	assert.Panics(t, func() { SlotOffsetForChar('X') })
+}
@@ -0,0 +1,192 @@
+package common
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Extension is the ISA extension or base ISA part an insn belongs to, as
+// recorded by the name of the insn description file.
+type Extension int
+
+const (
+	ExtUnknown    Extension = 0
+	ExtBase       Extension = 1
+	ExtFP         Extension = 2
+	ExtBitops     Extension = 3
+	ExtAtomics    Extension = 4
+	ExtBound      Extension = 5
+	ExtMul        Extension = 6
+	ExtPrivileged Extension = 7
+	ExtLSX        Extension = 8
+	ExtLASX       Extension = 9
+	ExtLBT        Extension = 10
+	ExtLVZ        Extension = 11
+)
+
+var extNames = map[Extension]string{
+	ExtBase:       "base",
+	ExtFP:         "fp",
+	ExtBitops:     "bitops",
+	ExtAtomics:    "atomics",
+	ExtBound:      "bound",
+	ExtMul:        "mul",
+	ExtPrivileged: "privileged",
+	ExtLSX:        "lsx",
+	ExtLASX:       "lasx",
+	ExtLBT:        "lbt",
+	ExtLVZ:        "lvz",
+}
+
+func (e Extension) String() string {
+	if name, ok := extNames[e]; ok {
+		return name
+	}
+	return fmt.Sprintf("<unknown Extension %d>", int(e))
+}
+
+func ParseExtension(s string) (Extension, error) {
+	for e, name := range extNames {
+		if name == s {
+			return e, nil
+		}
+	}
+	return ExtUnknown, fmt.Errorf("unknown extension %s", strconv.Quote(s))
+}
+
+// Width is the width of the base ISA an insn is available in.
+//
+// LA32 insns are available in LA64 as well.
+type Width int
+
+const (
+	WidthUnknown Width = 0
+	WidthLA32    Width = 32
+	WidthLA64    Width = 64
+)
+
+func (w Width) String() string {
+	switch w {
+	case WidthLA32:
+		return "la32"
+	case WidthLA64:
+		return "la64"
+	default:
+		return fmt.Sprintf("<unknown Width %d>", int(w))
+	}
+}
+
+// SubsetForPath returns the extension and width of the insns described in
+// the file at path, according to the file name.
+//
+// Files are named like "la-<ext>[-<variant>][-<width>].txt" for the base
+// ISA parts, and "<ext>.txt" for the extensions. Files with a "-32" suffix
+// contain LA32 insns, and so do the FP ones, which have no width suffix as
+// the FP insns are available in LA32 too. All other insns are considered
+// LA64-only.
+func SubsetForPath(path string) (Extension, Width, error) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	parts := strings.Split(name, "-")
+
+	extName := parts[0]
+	if extName == "la" && len(parts) > 1 {
+		extName = parts[1]
+	}
+
+	ext, err := ParseExtension(extName)
+	if err != nil {
+		return ExtUnknown, WidthUnknown, fmt.Errorf("%s: %w", path, err)
+	}
+
+	width := WidthLA64
+	if parts[len(parts)-1] == "32" || ext == ExtFP {
+		width = WidthLA32
+	}
+
+	return ext, width, nil
+}
+
+// Selector selects insns by width and extension.
+type Selector struct {
+	// Width, if not WidthUnknown, restricts the selection to insns
+	// available in the base ISA of the width.
+	Width Width
+	// Include, if non-empty, restricts the selection to insns of these
+	// extensions.
+	Include []Extension
+	// Exclude removes insns of these extensions from the selection.
+	Exclude []Extension
+}
+
+// ParseSelector parses a comma-separated list of selector terms, like
+// "la64,base,lsx" or "la32,!lbt".
+//
+// The terms "la32" and "la64" set the width; an extension name includes
+// the extension, and an extension name prefixed with "!" excludes it.
+func ParseSelector(s string) (*Selector, error) {
+	var result Selector
+	if s == "" {
+		return &result, nil
+	}
+
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+
+		switch term {
+		case "la32":
+			result.Width = WidthLA32
+			continue
+		case "la64":
+			result.Width = WidthLA64
+			continue
+		}
+
+		exclude := strings.HasPrefix(term, "!")
+		ext, err := ParseExtension(strings.TrimPrefix(term, "!"))
+		if err != nil {
+			return nil, fmt.Errorf("bad selector term %s: %w", strconv.Quote(term), err)
+		}
+
+		if exclude {
+			result.Exclude = append(result.Exclude, ext)
+		} else {
+			result.Include = append(result.Include, ext)
+		}
+	}
+
+	return &result, nil
+}
+
+func (s *Selector) Matches(d *InsnDescription) bool {
+	if s.Width == WidthLA32 && d.Width != WidthLA32 {
+		return false
+	}
+
+	if len(s.Include) > 0 && !containsExt(s.Include, d.Ext) {
+		return false
+	}
+
+	return !containsExt(s.Exclude, d.Ext)
+}
+
+func containsExt(exts []Extension, e Extension) bool {
+	for _, x := range exts {
+		if x == e {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the insns matched by the selector, preserving order.
+func Select(descs []*InsnDescription, s *Selector) []*InsnDescription {
+	var result []*InsnDescription
+	for _, d := range descs {
+		if s.Matches(d) {
+			result = append(result, d)
+		}
+	}
+	return result
+}
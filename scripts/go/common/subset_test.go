@@ -0,0 +1,123 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubsetForPath(t *testing.T) {
+	testcases := []struct {
+		path  string
+		ext   Extension
+		width Width
+	}{
+		{"../../la-base-32.txt", ExtBase, WidthLA32},
+		{"la-base-64.txt", ExtBase, WidthLA64},
+		{"la-fp.txt", ExtFP, WidthLA32},
+		{"la-fp-s.txt", ExtFP, WidthLA32},
+		{"la-bound-fp-d.txt", ExtBound, WidthLA64},
+		{"la-privileged-32.txt", ExtPrivileged, WidthLA32},
+		{"lsx.txt", ExtLSX, WidthLA64},
+		{"lvz.txt", ExtLVZ, WidthLA64},
+	}
+
+	for _, tc := range testcases {
+		ext, width, err := SubsetForPath(tc.path)
+		assert.NoError(t, err, tc.path)
+		assert.Equal(t, tc.ext, ext, tc.path)
+		assert.Equal(t, tc.width, width, tc.path)
+	}
+
+	for _, path := range []string{"foo.txt", "la.txt", "la-simd-64.txt"} {
+		_, _, err := SubsetForPath(path)
+		assert.Error(t, err, path)
+	}
+}
+
+func TestSelectRealTables(t *testing.T) {
+	// tests are run in the package directory, one level below the commands
+	descs, err := ReadAllInsnDescs(filepath.Join("..", TablesDir))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, descs)
+
+	sel, err := ParseSelector("la32")
+	assert.NoError(t, err)
+
+	selected := make(map[string]bool)
+	for _, d := range Select(descs, sel) {
+		selected[d.Mnemonic] = true
+	}
+
+	// FP insns are available in LA32
+	for _, m := range []string{"fadd.s", "fld.s", "movgr2fr.w", "movgr2frh.w", "fadd.d", "fsel", "bceqz"} {
+		assert.True(t, selected[m], m)
+	}
+
+	for _, m := range []string{"add.d", "ldx.d", "amswap.d", "mul.d", "ldgt.w", "vadd.b"} {
+		assert.False(t, selected[m], m)
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	s, err := ParseSelector("la64, base,lsx,!lbt")
+	assert.NoError(t, err)
+	assert.Equal(t, &Selector{
+		Width:   WidthLA64,
+		Include: []Extension{ExtBase, ExtLSX},
+		Exclude: []Extension{ExtLBT},
+	}, s)
+
+	s, err = ParseSelector("")
+	assert.NoError(t, err)
+	assert.Equal(t, &Selector{}, s)
+
+	for _, sel := range []string{"la128", "base,", "!!lbt", "LSX"} {
+		_, err := ParseSelector(sel)
+		assert.Error(t, err, sel)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"la-base-32.txt": "00100000 add.w                  DJK\n",
+		"la-base-64.txt": "00108000 add.d                  DJK\n",
+		"lsx.txt":        "700a0000 vadd.b                 VdVjVk\n",
+		"lbt.txt":        "00000800 movgr2scr              CdJ\n",
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		assert.NoError(t, err)
+	}
+
+	descs, err := ReadAllInsnDescs(dir)
+	assert.NoError(t, err)
+
+	mnemonics := func(s string) []string {
+		sel, err := ParseSelector(s)
+		assert.NoError(t, err)
+
+		var result []string
+		for _, d := range Select(descs, sel) {
+			result = append(result, d.Mnemonic)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"add.w", "add.d", "movgr2scr", "vadd.b"}, mnemonics(""))
+	assert.Equal(t, []string{"add.w"}, mnemonics("la32"))
+	assert.Equal(t, []string{"add.w", "add.d", "vadd.b"}, mnemonics("la64,base,lsx"))
+	assert.Equal(t, []string{"add.w", "add.d", "vadd.b"}, mnemonics("!lbt"))
+	assert.Nil(t, mnemonics("la32,lsx"))
+
+	for _, d := range descs {
+		if d.Mnemonic == "vadd.b" {
+			assert.Equal(t, "lsx.txt", d.Source)
+			assert.Equal(t, ExtLSX, d.Ext)
+			assert.Equal(t, WidthLA64, d.Width)
+		}
+	}
+}
@@ -0,0 +1,578 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TableGenInsn is an insn encoding defined in LLVM TableGen sources, like
+// the LoongArch backend's LoongArchInstrInfo.td.
+type TableGenInsn struct {
+	// Name is the name of the record, like "ADD_W".
+	Name string
+	// Path and Line locate the def of the record.
+	Path string
+	Line int
+
+	Word uint32
+	Mask uint32
+	Args []*TableGenArg
+
+	// Err is set if the encoding could not be evaluated, in which case the
+	// other fields are not meaningful.
+	Err error
+}
+
+// TableGenArg is an operand field of a TableGenInsn.
+type TableGenArg struct {
+	// Name is the name of the field, like "rd" or "imm12".
+	Name string
+	// Kind is ArgKindUnknown if the operand type is not recognized.
+	Kind ArgKind
+	// Slots are ordered from MSB to LSB, like in Arg.
+	//
+	// Args of a TableGenInsn are ordered by the offsets of their last slots.
+	Slots []*Slot
+}
+
+// Bitmask returns the bits of the insn word occupied by the arg.
+func (a *TableGenArg) Bitmask() uint32 {
+	var result uint32
+	for _, s := range a.Slots {
+		result |= s.Bitmask()
+	}
+	return result
+}
+
+// Mnemonic returns the assembly mnemonic derived from the record name the
+// same way the LoongArch backend does, e.g. "AMSWAP__DB_W" becomes
+// "amswap_db.w".
+func (i *TableGenInsn) Mnemonic() string {
+	s := strings.ReplaceAll(i.Name, "__", "@")
+	s = strings.ReplaceAll(s, "_", ".")
+	s = strings.ReplaceAll(s, "@", "_")
+	return strings.ToLower(s)
+}
+
+// ReadTableGenInsns reads all records with an insn encoding from the given
+// TableGen sources.
+//
+// Only the subset of TableGen needed for evaluating encodings is
+// understood: classes, and defs deriving from them, assigning bits of the
+// "Inst" field from template args, literals and operand fields. Multiclasses
+// and defm are not expanded.
+func ReadTableGenInsns(paths []string) ([]*TableGenInsn, error) {
+	recs := &tgRecords{
+		classes: make(map[string]*tgClass),
+	}
+
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		err = recs.parse(path, string(src))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var result []*TableGenInsn
+	for _, def := range recs.defs {
+		insn := recs.eval(def)
+		if insn != nil {
+			result = append(result, insn)
+		}
+	}
+
+	return result, nil
+}
+
+type tgRecords struct {
+	classes map[string]*tgClass
+	defs    []*tgDef
+}
+
+type tgClass struct {
+	name    string
+	params  []string
+	parents []*tgRef
+	body    string
+}
+
+type tgDef struct {
+	name    string
+	path    string
+	line    int
+	parents []*tgRef
+	body    string
+}
+
+// tgRef is a reference to a class with template args, like "Fmt3R<op>".
+type tgRef struct {
+	name string
+	args []string
+}
+
+var tgCommentRE = regexp.MustCompile(`(?s)//[^\n]*|/\*.*?\*/`)
+var tgRecordStartRE = regexp.MustCompile(`\b(class|def)\s+([A-Za-z_][0-9A-Za-z_]*)\s*`)
+
+func (r *tgRecords) parse(path string, src string) error {
+	// blank out comments, keeping offsets and newlines intact
+	src = tgCommentRE.ReplaceAllStringFunc(src, func(s string) string {
+		return strings.Map(func(c rune) rune {
+			if c == '\n' {
+				return c
+			}
+			return ' '
+		}, s)
+	})
+
+	pos := 0
+	for {
+		loc := tgRecordStartRE.FindStringSubmatchIndex(src[pos:])
+		if loc == nil {
+			return nil
+		}
+
+		start := pos + loc[0]
+		keyword := src[pos+loc[2] : pos+loc[3]]
+		name := src[pos+loc[4] : pos+loc[5]]
+		i := pos + loc[1]
+		line := strings.Count(src[:start], "\n") + 1
+
+		makeErr := func(msg string) error {
+			return fmt.Errorf("%s:%d: %s %s: %s", path, line, keyword, name, msg)
+		}
+
+		var params []string
+		if keyword == "class" && i < len(src) && src[i] == '<' {
+			end := tgFindClose(src, i)
+			if end == -1 {
+				return makeErr("unterminated template params")
+			}
+			params = tgParseParams(src[i+1 : end])
+			i = end + 1
+		}
+
+		var parents []*tgRef
+		i = tgSkipSpace(src, i)
+		if i < len(src) && src[i] == ':' {
+			end := tgFindTopLevel(src, i+1, "{;")
+			if end == -1 {
+				return makeErr("unterminated parent class list")
+			}
+			parents = tgParseRefs(src[i+1 : end])
+			i = end
+		}
+
+		body := ""
+		if i < len(src) && src[i] == '{' {
+			end := tgFindClose(src, i)
+			if end == -1 {
+				return makeErr("unterminated body")
+			}
+			body = src[i+1 : end]
+			i = end + 1
+		}
+
+		if keyword == "class" {
+			r.classes[name] = &tgClass{
+				name:    name,
+				params:  params,
+				parents: parents,
+				body:    body,
+			}
+		} else {
+			r.defs = append(r.defs, &tgDef{
+				name:    name,
+				path:    path,
+				line:    line,
+				parents: parents,
+				body:    body,
+			})
+		}
+
+		pos = i
+	}
+}
+
+func tgSkipSpace(s string, i int) int {
+	for i < len(s) && strings.IndexByte(" \t\r\n", s[i]) != -1 {
+		i++
+	}
+	return i
+}
+
+// tgFindTopLevel returns the index of the first of the stop chars found
+// outside any brackets or string literals, starting from i, or -1.
+func tgFindTopLevel(s string, i int, stops string) int {
+	depth := 0
+	for ; i < len(s); i++ {
+		c := s[i]
+		if depth == 0 && strings.IndexByte(stops, c) != -1 {
+			return i
+		}
+
+		switch c {
+		case '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end == -1 {
+				return -1
+			}
+			i += end + 1
+		case '<', '(', '[', '{':
+			depth++
+		case '>', ')', ']', '}':
+			depth--
+		}
+	}
+	return -1
+}
+
+// tgFindClose returns the index of the bracket closing the one at i, or -1.
+func tgFindClose(s string, i int) int {
+	return tgFindTopLevel(s, i+1, ">)]}")
+}
+
+// tgSplitTopLevel splits s at commas outside any brackets.
+func tgSplitTopLevel(s string) []string {
+	var result []string
+	for {
+		end := tgFindTopLevel(s, 0, ",")
+		if end == -1 {
+			break
+		}
+		result = append(result, strings.TrimSpace(s[:end]))
+		s = s[end+1:]
+	}
+
+	if s = strings.TrimSpace(s); s != "" {
+		result = append(result, s)
+	}
+	return result
+}
+
+var tgParamNameRE = regexp.MustCompile(`([A-Za-z_][0-9A-Za-z_]*)\s*(?:=.*)?$`)
+
+func tgParseParams(s string) []string {
+	var result []string
+	for _, p := range tgSplitTopLevel(s) {
+		m := tgParamNameRE.FindStringSubmatch(p)
+		if m == nil {
+			result = append(result, "")
+			continue
+		}
+		result = append(result, m[1])
+	}
+	return result
+}
+
+func tgParseRefs(s string) []*tgRef {
+	var result []*tgRef
+	for _, r := range tgSplitTopLevel(s) {
+		ref := &tgRef{name: r}
+		if idx := strings.IndexByte(r, '<'); idx != -1 && strings.HasSuffix(r, ">") {
+			ref.name = strings.TrimSpace(r[:idx])
+			ref.args = tgSplitTopLevel(r[idx+1 : len(r)-1])
+		}
+		result = append(result, ref)
+	}
+	return result
+}
+
+// tgBit is the evaluated value of one bit of the "Inst" field.
+type tgBit struct {
+	set      bool
+	field    string
+	fieldBit int
+	val      uint32
+}
+
+type tgEvalCtx struct {
+	bits  [32]tgBit
+	types map[string]string
+	// set if any bit of Inst is assigned
+	isInsn bool
+}
+
+var tgIdentRE = regexp.MustCompile(`\b[A-Za-z_][0-9A-Za-z_]*\b`)
+var tgInstLetRE = regexp.MustCompile(`\blet\s+Inst\s*\{\s*([0-9]+)\s*(?:-\s*([0-9]+)\s*)?\}\s*=\s*([^;]+);`)
+var tgOperandTypeRE = regexp.MustCompile(`([A-Za-z_][0-9A-Za-z_]*)\s*:\s*\$([A-Za-z_][0-9A-Za-z_]*)`)
+var tgValueRE = regexp.MustCompile(`^([0-9A-Za-z_]+)\s*(?:\{\s*([0-9]+)\s*(?:-\s*([0-9]+)\s*)?\})?$`)
+
+// eval evaluates the encoding of a def, returning nil if the def does not
+// assign bits of Inst.
+func (r *tgRecords) eval(def *tgDef) *TableGenInsn {
+	ctx := tgEvalCtx{
+		types: make(map[string]string),
+	}
+
+	result := &TableGenInsn{
+		Name: def.name,
+		Path: def.path,
+		Line: def.line,
+	}
+
+	var err error
+	for _, p := range def.parents {
+		err = r.evalRef(&ctx, p, 0)
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = ctx.applyBody(def.body, nil)
+	}
+
+	if !ctx.isInsn {
+		return nil
+	}
+
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Err = ctx.build(result)
+	return result
+}
+
+func (r *tgRecords) evalRef(ctx *tgEvalCtx, ref *tgRef, depth int) error {
+	if depth > 64 {
+		return fmt.Errorf("class %s: inheritance too deep", ref.name)
+	}
+
+	cls, ok := r.classes[ref.name]
+	if !ok {
+		// classes not defined in the sources, like Requires<...>, have no
+		// effect on encodings
+		return nil
+	}
+
+	for _, arg := range ref.args {
+		for _, m := range tgOperandTypeRE.FindAllStringSubmatch(arg, -1) {
+			if _, ok := ctx.types[m[2]]; !ok {
+				ctx.types[m[2]] = m[1]
+			}
+		}
+	}
+
+	env := make(map[string]string, len(cls.params))
+	for i, p := range cls.params {
+		if i < len(ref.args) && p != "" {
+			env[p] = ref.args[i]
+		}
+	}
+
+	subst := func(s string) string {
+		return tgIdentRE.ReplaceAllStringFunc(s, func(id string) string {
+			if v, ok := env[id]; ok {
+				return v
+			}
+			return id
+		})
+	}
+
+	for _, p := range cls.parents {
+		args := make([]string, len(p.args))
+		for i, a := range p.args {
+			args[i] = subst(a)
+		}
+
+		err := r.evalRef(ctx, &tgRef{name: p.name, args: args}, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := ctx.applyBody(cls.body, env)
+	if err != nil {
+		return fmt.Errorf("class %s: %w", cls.name, err)
+	}
+
+	return nil
+}
+
+// applyBody applies the assignments to Inst in a record body, template args
+// being looked up in env.
+func (ctx *tgEvalCtx) applyBody(body string, env map[string]string) error {
+	for _, m := range tgInstLetRE.FindAllStringSubmatch(body, -1) {
+		ctx.isInsn = true
+
+		hi, _ := strconv.Atoi(m[1])
+		lo := hi
+		if m[2] != "" {
+			lo, _ = strconv.Atoi(m[2])
+		}
+		if hi < lo || hi > 31 {
+			return fmt.Errorf("bad bit range Inst{%d-%d}", hi, lo)
+		}
+
+		err := ctx.assign(hi, lo, strings.TrimSpace(m[3]), env)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ctx *tgEvalCtx) assign(hi int, lo int, rhs string, env map[string]string) error {
+	m := tgValueRE.FindStringSubmatch(rhs)
+	if m == nil {
+		return fmt.Errorf("unsupported value %s", strconv.Quote(rhs))
+	}
+
+	name := m[1]
+	if v, ok := env[name]; ok {
+		// template args must be plain values for bit ranges to be meaningful
+		vm := tgValueRE.FindStringSubmatch(strings.TrimSpace(v))
+		if vm == nil || vm[2] != "" {
+			return fmt.Errorf("unsupported value %s", strconv.Quote(v))
+		}
+		name = vm[1]
+	}
+
+	width := hi - lo + 1
+	srcLo := 0
+	if m[2] != "" {
+		srcHi, _ := strconv.Atoi(m[2])
+		srcLo = srcHi
+		if m[3] != "" {
+			srcLo, _ = strconv.Atoi(m[3])
+		}
+		if srcHi-srcLo+1 != width {
+			return fmt.Errorf("width mismatch assigning %s to Inst{%d-%d}", rhs, hi, lo)
+		}
+	}
+
+	if lit, ok := tgParseLiteral(name); ok {
+		for i := 0; i < width; i++ {
+			ctx.bits[lo+i] = tgBit{
+				set: true,
+				val: uint32(lit>>(srcLo+i)) & 1,
+			}
+		}
+		return nil
+	}
+
+	for i := 0; i < width; i++ {
+		ctx.bits[lo+i] = tgBit{
+			set:      true,
+			field:    name,
+			fieldBit: srcLo + i,
+		}
+	}
+	return nil
+}
+
+func tgParseLiteral(s string) (uint64, bool) {
+	var v uint64
+	var err error
+	switch {
+	case strings.HasPrefix(s, "0b"):
+		v, err = strconv.ParseUint(s[2:], 2, 64)
+	case strings.HasPrefix(s, "0x"):
+		v, err = strconv.ParseUint(s[2:], 16, 64)
+	default:
+		v, err = strconv.ParseUint(s, 10, 64)
+	}
+	return v, err == nil
+}
+
+// build fills in the encoding of insn from the evaluated bits.
+func (ctx *tgEvalCtx) build(insn *TableGenInsn) error {
+	type fieldBit struct {
+		instBit  int
+		fieldBit int
+	}
+	fields := make(map[string][]fieldBit)
+
+	for i, b := range ctx.bits {
+		if !b.set {
+			return fmt.Errorf("Inst{%d} is not assigned", i)
+		}
+
+		if b.field == "" {
+			insn.Mask |= 1 << i
+			insn.Word |= b.val << i
+			continue
+		}
+
+		fields[b.field] = append(fields[b.field], fieldBit{i, b.fieldBit})
+	}
+
+	for name, fbs := range fields {
+		// slots are ordered from MSB to LSB of the field
+		sort.Slice(fbs, func(i int, j int) bool {
+			return fbs[i].fieldBit > fbs[j].fieldBit
+		})
+
+		var slots []*Slot
+		for i := 0; i < len(fbs); {
+			j := i + 1
+			for j < len(fbs) &&
+				fbs[j].fieldBit == fbs[j-1].fieldBit-1 &&
+				fbs[j].instBit == fbs[j-1].instBit-1 {
+				j++
+			}
+
+			slots = append(slots, &Slot{
+				Offset: uint(fbs[j-1].instBit),
+				Width:  uint(j - i),
+			})
+			i = j
+		}
+
+		if fbs[len(fbs)-1].fieldBit != 0 {
+			return errors.New("low bits of field " + name + " are not encoded")
+		}
+
+		insn.Args = append(insn.Args, &TableGenArg{
+			Name:  name,
+			Kind:  tgArgKind(ctx.types[name]),
+			Slots: slots,
+		})
+	}
+
+	// order args by the offsets of their last slots
+	sort.Slice(insn.Args, func(i int, j int) bool {
+		return insn.Args[i].Slots[len(insn.Args[i].Slots)-1].Offset <
+			insn.Args[j].Slots[len(insn.Args[j].Slots)-1].Offset
+	})
+
+	return nil
+}
+
+// tgArgKind returns the kind of operands of the given TableGen operand type.
+func tgArgKind(typ string) ArgKind {
+	switch {
+	case strings.HasPrefix(typ, "GPR"):
+		return ArgKindIntReg
+	case strings.HasPrefix(typ, "FPR"):
+		return ArgKindFPReg
+	case strings.HasPrefix(typ, "CFR"):
+		return ArgKindFCCReg
+	case strings.HasPrefix(typ, "SCR"):
+		return ArgKindScratchReg
+	case strings.HasPrefix(typ, "LSX"):
+		return ArgKindVReg
+	case strings.HasPrefix(typ, "LASX"):
+		return ArgKindXReg
+	case strings.HasPrefix(typ, "simm"):
+		return ArgKindSignedImm
+	case strings.HasPrefix(typ, "uimm"),
+		// FCSR operands are unsigned immediates in insn formats
+		strings.HasPrefix(typ, "FCSR"):
+		return ArgKindUnsignedImm
+	default:
+		return ArgKindUnknown
+	}
+}
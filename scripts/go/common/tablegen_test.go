@@ -0,0 +1,131 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testTableGenFormats = `
+class LAInst<dag outs, dag ins, string opcstr, string opnstr,
+             list<dag> pattern = []>
+    : Instruction {
+  field bits<32> Inst;
+  let AsmString = opcstr # "\t" # opnstr;
+}
+
+// 3R-type
+class Fmt3R<bits<32> op, dag outs, dag ins, string opnstr>
+    : LAInst<outs, ins, deriveInsnMnemonic<NAME>.ret, opnstr> {
+  bits<5> rk;
+  bits<5> rj;
+  bits<5> rd;
+
+  let Inst{31-0} = op;
+  let Inst{14-10} = rk;
+  let Inst{9-5} = rj;
+  let Inst{4-0} = rd;
+}
+
+class FmtI26<bits<32> op, dag outs, dag ins, string opnstr>
+    : LAInst<outs, ins, deriveInsnMnemonic<NAME>.ret, opnstr> {
+  bits<26> imm26;
+
+  let Inst{31-0} = op;
+  let Inst{25-10} = imm26{15-0};
+  let Inst{9-0} = imm26{25-16};
+}
+
+/* BCEQZ-type, with fixed bits inside the operand area */
+class FmtBCZ<bits<32> op, dag outs, dag ins, string opnstr>
+    : LAInst<outs, ins, deriveInsnMnemonic<NAME>.ret, opnstr> {
+  bits<21> imm21;
+  bits<3> cj;
+
+  let Inst{31-0} = op;
+  let Inst{25-10} = imm21{15-0};
+  let Inst{9-8} = 0b00;
+  let Inst{7-5} = cj;
+  let Inst{4-0} = imm21{20-16};
+}
+
+class FmtBad<bits<32> op> : LAInst<(outs), (ins), "", ""> {
+  let Inst{31-16} = op{31-16};
+}
+`
+
+const testTableGenInsns = `
+class ALU_3R<bits<32> op>
+    : Fmt3R<op, (outs GPR:$rd), (ins GPR:$rj, GPR:$rk), "$rd, $rj, $rk">;
+
+let hasSideEffects = 0 in {
+def AMSWAP__DB_W : ALU_3R<0x38690000>;
+def B : FmtI26<0x50000000, (outs), (ins simm26_b:$imm26), "$imm26"> {
+  let isBranch = 1;
+}
+}
+def BCEQZ : FmtBCZ<0x48000000, (outs), (ins CFR:$cj, simm21_lsl2:$imm21), "$cj, $imm21">;
+def BAD : FmtBad<0x12340000>;
+def simm12 : Operand<GRLenVT>;
+def : Pat<(add GPR:$rj, GPR:$rk), (ADD_W GPR:$rj, GPR:$rk)>;
+`
+
+func TestReadTableGenInsns(t *testing.T) {
+	dir := t.TempDir()
+	formatsPath := filepath.Join(dir, "LoongArchInstrFormats.td")
+	insnsPath := filepath.Join(dir, "LoongArchInstrInfo.td")
+	assert.NoError(t, os.WriteFile(formatsPath, []byte(testTableGenFormats), 0644))
+	assert.NoError(t, os.WriteFile(insnsPath, []byte(testTableGenInsns), 0644))
+
+	insns, err := ReadTableGenInsns([]string{formatsPath, insnsPath})
+	assert.NoError(t, err)
+	if !assert.Len(t, insns, 4) {
+		return
+	}
+
+	amswap := insns[0]
+	assert.Equal(t, "AMSWAP__DB_W", amswap.Name)
+	assert.Equal(t, "amswap_db.w", amswap.Mnemonic())
+	assert.Equal(t, insnsPath, amswap.Path)
+	assert.Equal(t, 6, amswap.Line)
+	assert.NoError(t, amswap.Err)
+	assert.Equal(t, uint32(0x38690000), amswap.Word)
+	assert.Equal(t, uint32(0xffff8000), amswap.Mask)
+	assert.Equal(t, []*TableGenArg{
+		{Name: "rd", Kind: ArgKindIntReg, Slots: []*Slot{{Offset: 0, Width: 5}}},
+		{Name: "rj", Kind: ArgKindIntReg, Slots: []*Slot{{Offset: 5, Width: 5}}},
+		{Name: "rk", Kind: ArgKindIntReg, Slots: []*Slot{{Offset: 10, Width: 5}}},
+	}, amswap.Args)
+
+	b := insns[1]
+	assert.Equal(t, "b", b.Mnemonic())
+	assert.NoError(t, b.Err)
+	assert.Equal(t, uint32(0x50000000), b.Word)
+	assert.Equal(t, uint32(0xfc000000), b.Mask)
+	assert.Equal(t, []*TableGenArg{
+		{
+			Name:  "imm26",
+			Kind:  ArgKindSignedImm,
+			Slots: []*Slot{{Offset: 0, Width: 10}, {Offset: 10, Width: 16}},
+		},
+	}, b.Args)
+
+	bceqz := insns[2]
+	assert.NoError(t, bceqz.Err)
+	assert.Equal(t, uint32(0x48000000), bceqz.Word)
+	assert.Equal(t, uint32(0xfc000300), bceqz.Mask)
+	assert.Equal(t, []*TableGenArg{
+		{Name: "cj", Kind: ArgKindFCCReg, Slots: []*Slot{{Offset: 5, Width: 3}}},
+		{
+			Name:  "imm21",
+			Kind:  ArgKindSignedImm,
+			Slots: []*Slot{{Offset: 0, Width: 5}, {Offset: 10, Width: 16}},
+		},
+	}, bceqz.Args)
+
+	// Inst{15-0} is left unassigned
+	assert.Equal(t, "BAD", insns[3].Name)
+	assert.Error(t, insns[3].Err)
+}
@@ -0,0 +1,97 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// column widths of the insn description line format
+const (
+	insnWordAndMnemonicWidth = 32
+	insnFormatWidth          = 16
+)
+
+// FormatInsnDescriptionLine renders the insn description as a line of the
+// table format, without the trailing newline. It is the inverse of
+// ParseInsnDescriptionLine.
+//
+// Columns are aligned like the hand-written tables, and attribs are written
+// in canonical order.
+func FormatInsnDescriptionLine(d *InsnDescription) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%08x %s", d.Word, d.Mnemonic)
+
+	attribs := formatInsnAttribs(d)
+
+	sb.WriteString(padding(sb.Len(), insnWordAndMnemonicWidth))
+	formatStart := sb.Len()
+	sb.WriteString(d.Format.CanonicalRepr())
+
+	if len(attribs) == 0 {
+		return sb.String()
+	}
+
+	sb.WriteString(padding(sb.Len()-formatStart, insnFormatWidth))
+	sb.WriteString(strings.Join(attribs, " "))
+
+	return sb.String()
+}
+
+// padding returns the spaces needed to pad a column of width w to at least
+// the given width, always separating columns by at least one space.
+func padding(w int, width int) string {
+	if w >= width {
+		return " "
+	}
+	return strings.Repeat(" ", width-w)
+}
+
+// formatInsnAttribs returns the attribs of the insn in canonical order,
+// "@orig_fmt" included.
+func formatInsnAttribs(d *InsnDescription) []string {
+	a := &d.Attribs
+
+	var result []string
+	if a.LBT {
+		result = append(result, "@lbt")
+	}
+	if a.LVZ {
+		result = append(result, "@lvz")
+	}
+	if a.OrigName != "" {
+		result = append(result, "@orig_name="+a.OrigName)
+	}
+	if d.OrigFormat != nil {
+		result = append(result, "@"+origFmtKey+"="+d.OrigFormat.CanonicalRepr())
+	}
+	if a.LA32 {
+		result = append(result, "@la32")
+	}
+	if a.Primary {
+		result = append(result, "@primary")
+	}
+	if a.QEMU {
+		result = append(result, "@qemu")
+	}
+	if a.Provisional {
+		result = append(result, "@provisional")
+	}
+	if !a.Rev.IsZero() {
+		result = append(result, "@rev="+a.Rev.String())
+	}
+
+	return result
+}
+
+// WriteInsnDescriptions writes the insn descriptions to w, one line each.
+func WriteInsnDescriptions(w io.Writer, descs []*InsnDescription) error {
+	bw := bufio.NewWriter(w)
+	for _, d := range descs {
+		bw.WriteString(FormatInsnDescriptionLine(d))
+		bw.WriteRune('\n')
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,87 @@
+package common
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatInsnDescriptionLine(t *testing.T) {
+	testcases := []struct {
+		x        string
+		expected string
+	}{
+		{
+			x:        "00100000 add.w                  DJK             @la32 @primary @qemu",
+			expected: "00100000 add.w                  DJK             @la32 @primary @qemu",
+		},
+		{
+			x:        "06483800 eret                   EMPTY",
+			expected: "06483800 eret                   EMPTY",
+		},
+		{
+			x:        "60000000 bgt                    DJSk16          @orig_name=blt @orig_fmt=JDSk16ps2 @la32 @primary @qemu",
+			expected: "60000000 bgt                    DJSk16          @orig_name=blt @orig_fmt=JDSk16ps2 @la32 @primary @qemu",
+		},
+		{
+			x:        "38590000 amcas.w                DJK             @orig_fmt=DKJ @rev=1p10",
+			expected: "38590000 amcas.w                DJK             @orig_fmt=DKJ @rev=1p10",
+		},
+		// alignment and attrib order are canonicalized
+		{
+			x:        "00100000 add.w DJK @qemu @primary @la32",
+			expected: "00100000 add.w                  DJK             @la32 @primary @qemu",
+		},
+		{
+			x:        "00000800 movgr2scr              TdJ  @rev=1p10 @provisional @orig_name=foo @lbt",
+			expected: "00000800 movgr2scr              TdJ             @lbt @orig_name=foo @provisional @rev=1p10",
+		},
+		// overlong columns are separated by one space
+		{
+			x:        "00100000 a.very.long.mnemonic.foo DJK @qemu",
+			expected: "00100000 a.very.long.mnemonic.foo DJK             @qemu",
+		},
+		{
+			x:        "31100000 vstelm.d               VdJSk8Un1 @orig_fmt=VdJSk8ps3Un1",
+			expected: "31100000 vstelm.d               VdJSk8Un1       @orig_fmt=VdJSk8ps3Un1",
+		},
+		{
+			x:        "00000000 foo                    DSj5k5Ua1Um2Un10   @qemu",
+			expected: "00000000 foo                    DSj5k5Ua1Um2Un10 @qemu",
+		},
+	}
+
+	for _, tc := range testcases {
+		d, err := ParseInsnDescriptionLine(tc.x)
+		if !assert.NoError(t, err, tc.x) {
+			continue
+		}
+
+		actual := FormatInsnDescriptionLine(d)
+		assert.Equal(t, tc.expected, actual)
+
+		// formatting must round-trip
+		d2, err := ParseInsnDescriptionLine(actual)
+		assert.NoError(t, err)
+		assert.Equal(t, d, d2)
+	}
+}
+
+func TestWriteInsnDescriptions(t *testing.T) {
+	descs := mustParseInsnDescriptionLines(
+		t,
+		"00100000 add.w                  DJK             @la32 @primary @qemu",
+		"06483800 eret                   EMPTY",
+	)
+
+	var buf bytes.Buffer
+	err := WriteInsnDescriptions(&buf, descs)
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		"00100000 add.w                  DJK             @la32 @primary @qemu\n"+
+			"06483800 eret                   EMPTY\n",
+		buf.String(),
+	)
+}
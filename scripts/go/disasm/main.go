@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+)
+
+var manual = flag.Bool("manual", false, "emit manual (binutils) syntax")
+
+// Disassembles hex insn words given as arguments, or one per line from stdin
+// if no argument is given.
+func main() {
+	flag.Parse()
+
+	descs, err := common.ReadAllInsnDescs(common.TablesDir)
+	if err != nil {
+		panic(err)
+	}
+
+	d := common.NewDecoder(descs)
+
+	words := flag.Args()
+	if len(words) == 0 {
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			l := strings.TrimSpace(sc.Text())
+			if len(l) == 0 {
+				continue
+			}
+			words = append(words, l)
+		}
+		if err := sc.Err(); err != nil {
+			panic(err)
+		}
+	}
+
+	failed := false
+	for _, w := range words {
+		word, err := strconv.ParseUint(strings.TrimPrefix(w, "0x"), 16, 32)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: malformed insn word\n", w)
+			failed = true
+			continue
+		}
+
+		disassemble := d.Disassemble
+		if *manual {
+			disassemble = d.DisassembleManual
+		}
+
+		text, err := disassemble(uint32(word))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			failed = true
+			continue
+		}
+
+		fmt.Printf("%08x\t%s\n", word, text)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+)
+
+var write = flag.Bool("w", false, "write result to the source files instead of checking them")
+
+// Reformats insn description files given as arguments, defaulting to all of
+// them. Without -w, the files whose formatting differs are listed instead,
+// and the exit status is 1 if there is any.
+func main() {
+	flag.Parse()
+
+	paths := flag.Args()
+	if len(paths) == 0 {
+		var err error
+		paths, err = filepath.Glob(filepath.Join(common.TablesDir, "*.txt"))
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	failed := false
+	for _, path := range paths {
+		changed, err := formatFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+
+		if changed && !*write {
+			fmt.Println(path)
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// formatFile reformats the file at path, writing it back if -w is given,
+// and reports whether the formatting differs.
+func formatFile(path string) (bool, error) {
+	orig, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	descs, err := common.ReadInsnDescriptionFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	var buf bytes.Buffer
+	err = common.WriteInsnDescriptions(&buf, descs)
+	if err != nil {
+		return false, err
+	}
+
+	if bytes.Equal(orig, buf.Bytes()) {
+		return false, nil
+	}
+
+	if *write {
+		err = os.WriteFile(path, buf.Bytes(), 0644)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
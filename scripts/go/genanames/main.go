@@ -1,16 +1,25 @@
 package main
 
 import (
+	"flag"
 	"os"
 	"sort"
 
 	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
 )
 
+var selector = flag.String(
+	"select",
+	"",
+	"only process insns matching this selector, e.g. \"la64,base,lsx\"",
+)
+
+// Takes the insn description files to process as arguments, defaulting to
+// all of them.
 func main() {
-	inputs := os.Args[1:]
+	flag.Parse()
 
-	descs, err := common.ReadInsnDescs(inputs)
+	descs, err := common.ReadSelectedInsnDescs(flag.Args(), *selector)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+)
+
+var selector = flag.String(
+	"select",
+	"",
+	"only process insns matching this selector, e.g. \"la64,base,lsx\"",
+)
+
+// Takes the insn description files to process as arguments, defaulting to
+// all of them.
+func main() {
+	flag.Parse()
+
+	descs, err := common.ReadSelectedInsnDescs(flag.Args(), *selector)
+	if err != nil {
+		panic(err)
+	}
+
+	sort.Slice(descs, func(i int, j int) bool {
+		return descs[i].Word < descs[j].Word
+	})
+
+	var ectx common.EmitterCtx
+	ectx.DontGofmt = true
+
+	ectx.Emit("/* SPDX-License-Identifier: MIT */\n")
+	ectx.Emit("/*\n")
+	ectx.Emit(" * LoongArch opcode tables.\n")
+	ectx.Emit(" *\n")
+	ectx.Emit(" * This file is auto-generated by genbinutils from\n")
+	ectx.Emit(" * https://github.com/loongson-community/loongarch-opcodes,\n")
+	ectx.Emit(" * from commit %s.\n", common.MustGetGitCommitHash())
+	ectx.Emit(" * DO NOT EDIT.\n")
+	ectx.Emit(" *\n")
+	ectx.Emit(" * NOTE: Paste into opcodes/loongarch-opc.c and adjust as necessary\n")
+	ectx.Emit(" * (add macros, aliases, etc.)\n")
+	ectx.Emit(" */\n")
+
+	emitted := 0
+	for _, t := range opcodeTables {
+		var tableDescs []*common.InsnDescription
+		for _, d := range descs {
+			if t.includes(d) {
+				tableDescs = append(tableDescs, d)
+			}
+		}
+
+		if len(tableDescs) == 0 {
+			continue
+		}
+
+		emitOpcodeTable(&ectx, t.name, tableDescs)
+		emitted += len(tableDescs)
+	}
+
+	if emitted != len(descs) {
+		panic(fmt.Sprintf("%d insns not belonging to any opcode table", len(descs)-emitted))
+	}
+
+	result := ectx.Finalize()
+	os.Stdout.Write(result)
+}
+
+// opcodeTable is an array of opcodes in binutils, grouping insns of some
+// table files that are enabled by the same ASE flag.
+type opcodeTable struct {
+	name    string
+	sources []string
+	// filter, if not nil, further restricts the insns of the table
+	filter func(d *common.InsnDescription) bool
+}
+
+func (t *opcodeTable) includes(d *common.InsnDescription) bool {
+	for _, s := range t.sources {
+		if s == d.Source {
+			return t.filter == nil || t.filter(d)
+		}
+	}
+	return false
+}
+
+// isFPLoadStore reports whether the insn transfers an FP register from or to
+// memory, i.e. has an FP register, a base GPR at rj, and either an offset or
+// an index GPR.
+func isFPLoadStore(d *common.InsnDescription) bool {
+	var hasFPReg, hasBase, hasOffset bool
+	for _, a := range d.Format.Args {
+		switch {
+		case a.Kind == common.ArgKindFPReg:
+			hasFPReg = true
+		case a.Kind == common.ArgKindIntReg && a.Slots[0].Offset == 5:
+			hasBase = true
+		case a.Kind == common.ArgKindIntReg || a.Kind.IsImm():
+			hasOffset = true
+		}
+	}
+	return hasFPReg && hasBase && hasOffset
+}
+
+func isNotFPLoadStore(d *common.InsnDescription) bool {
+	return !isFPLoadStore(d)
+}
+
+// inMajorOpcodeRange returns a filter selecting the insns whose word lies
+// in [lo, hi), for splitting the base ISA tables along the major opcode
+// ranges of the encoding table in the manual.
+func inMajorOpcodeRange(lo uint32, hi uint32) func(d *common.InsnDescription) bool {
+	return func(d *common.InsnDescription) bool {
+		return d.Word >= lo && d.Word < hi
+	}
+}
+
+// isMemAccess reports whether the insn is a base ISA memory access or
+// barrier insn, i.e. ll/sc, ldptr/stptr, ld/st, preld and the indexed,
+// atomic and bound-checking ones. FP ones are in separate tables anyway.
+func isMemAccess(d *common.InsnDescription) bool {
+	return inMajorOpcodeRange(0x20000000, 0x2c000000)(d) ||
+		inMajorOpcodeRange(0x38000000, 0x3c000000)(d)
+}
+
+// isJump reports whether the insn is a branch or jirl.
+var isJump = inMajorOpcodeRange(0x40000000, 0x70000000)
+
+// the opcode tables, named like in binutils
+//
+// Single- and double-precision FP insns are kept apart, as binutils enables
+// them with different ASE flags (ase_sf and ase_df).
+var opcodeTables = []*opcodeTable{
+	{
+		name: "loongarch_fix_opcodes",
+		sources: []string{
+			"la-base-32.txt", "la-base-64.txt",
+			"la-bitops-32.txt", "la-bitops-64.txt",
+			"la-mul-32.txt", "la-mul-64.txt",
+			// the asrt insns
+			"la-bound.txt",
+		},
+		filter: func(d *common.InsnDescription) bool {
+			return !isMemAccess(d) && !isJump(d)
+		},
+	},
+	{
+		name:    "loongarch_single_float_opcodes",
+		sources: []string{"la-fp.txt", "la-fp-s.txt"},
+		filter: func(d *common.InsnDescription) bool {
+			return !isJump(d) && !isFPLoadStore(d)
+		},
+	},
+	{
+		name:    "loongarch_double_float_opcodes",
+		sources: []string{"la-fp-d.txt"},
+		filter:  isNotFPLoadStore,
+	},
+	{
+		name: "loongarch_privilege_opcodes",
+		sources: []string{
+			"la-privileged-32.txt", "la-privileged-64.txt",
+		},
+	},
+	{
+		name:    "loongarch_jmp_opcodes",
+		sources: []string{"la-base-32.txt", "la-base-64.txt"},
+		filter:  isJump,
+	},
+	{
+		name: "loongarch_load_store_opcodes",
+		sources: []string{
+			"la-base-32.txt", "la-base-64.txt",
+			"la-atomics-32.txt", "la-atomics-64.txt",
+			"la-bound.txt", "la-bound-64.txt",
+		},
+		filter: isMemAccess,
+	},
+	{
+		name:    "loongarch_single_float_load_store_opcodes",
+		sources: []string{"la-fp-s.txt", "la-bound-fp-s.txt"},
+		filter:  isFPLoadStore,
+	},
+	{
+		name:    "loongarch_double_float_load_store_opcodes",
+		sources: []string{"la-fp-d.txt", "la-bound-fp-d.txt"},
+		filter:  isFPLoadStore,
+	},
+	{
+		name:    "loongarch_float_jmp_opcodes",
+		sources: []string{"la-fp.txt"},
+		filter:  isJump,
+	},
+	{
+		name:    "loongarch_lvz_opcodes",
+		sources: []string{"lvz.txt"},
+	},
+	{
+		name:    "loongarch_lsx_opcodes",
+		sources: []string{"lsx.txt"},
+	},
+	{
+		name:    "loongarch_lasx_opcodes",
+		sources: []string{"lasx.txt"},
+	},
+	{
+		name:    "loongarch_lbt_opcodes",
+		sources: []string{"lbt.txt"},
+	},
+}
+
+func emitOpcodeTable(ectx *common.EmitterCtx, name string, descs []*common.InsnDescription) {
+	ectx.Emit("\nstatic struct loongarch_opcode %s[] =\n", name)
+	ectx.Emit("{\n")
+	ectx.Emit("  /* match,    mask,       name,                    format,                         macro, include, exclude, pinfo.  */\n")
+
+	for _, d := range descs {
+		ectx.Emit(
+			"  { 0x%08x, 0x%08x, %-24s %-32s 0,     0,       0,       0 },\n",
+			d.Word,
+			d.Format.MatchBitmask(),
+			strconv.Quote(d.ManualMnemonic())+",",
+			strconv.Quote(d.BinutilsFormat())+",",
+		)
+	}
+
+	ectx.Emit("  { 0 } /* Terminate the list.  */\n")
+	ectx.Emit("};\n")
+}
@@ -0,0 +1,535 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+)
+
+var selector = flag.String(
+	"select",
+	"",
+	"only process insns matching this selector, e.g. \"la64,base,lsx\"",
+)
+
+var prefix = flag.String(
+	"prefix",
+	"la",
+	"prefix of all identifiers in the generated header",
+)
+
+// Takes the insn description files to process as arguments, defaulting to
+// all of them.
+//
+// Unlike genqemutcgdefs, the generated header is self-contained C99, only
+// depending on <stdint.h> and <assert.h>.
+func main() {
+	flag.Parse()
+
+	descs, err := common.ReadSelectedInsnDescs(flag.Args(), *selector)
+	if err != nil {
+		panic(err)
+	}
+
+	os.Stdout.Write(generate(descs))
+}
+
+// generate returns the header for the insns.
+func generate(descs []*common.InsnDescription) []byte {
+	formats := common.GatherFormats(descs)
+	scs := common.GatherSlotCombinations(formats)
+
+	sort.Slice(descs, func(i int, j int) bool {
+		return descs[i].Word < descs[j].Word
+	})
+
+	ectx := common.EmitterCtx{
+		DontGofmt: true,
+	}
+
+	guard := macroName("INSNS_H")
+
+	ectx.Emit("/* SPDX-License-Identifier: MIT */\n")
+	ectx.Emit("/*\n")
+	ectx.Emit(" * LoongArch instruction opcodes, formats, encoders and decoder.\n")
+	ectx.Emit(" *\n")
+	ectx.Emit(" * This file is auto-generated by genc from\n")
+	ectx.Emit(" * https://github.com/loongson-community/loongarch-opcodes,\n")
+	ectx.Emit(" * from commit %s.\n", common.MustGetGitCommitHash())
+	ectx.Emit(" * DO NOT EDIT.\n")
+	ectx.Emit(" *\n")
+	ectx.Emit(" * Define %s before including this file to customize the checking of\n", macroName("ASSERT"))
+	ectx.Emit(" * operand ranges, which defaults to assert().\n")
+	ectx.Emit(" */\n")
+	ectx.Emit("\n#ifndef %s\n#define %s\n", guard, guard)
+	ectx.Emit("\n#include <stdint.h>\n")
+	ectx.Emit("\n#ifndef %s\n", macroName("ASSERT"))
+	ectx.Emit("#include <assert.h>\n")
+	ectx.Emit("#define %s(x) assert(x)\n", macroName("ASSERT"))
+	ectx.Emit("#endif\n")
+
+	emitOpcEnum(&ectx, descs)
+	emitFmtEnum(&ectx, formats)
+	emitRangeCheckMacros(&ectx, formats)
+
+	for _, sc := range scs {
+		emitSlotEncoderFn(&ectx, sc)
+	}
+
+	for _, f := range formats {
+		emitFmtEncoderFn(&ectx, f)
+	}
+
+	for _, d := range descs {
+		emitEncoderForInsn(&ectx, d)
+	}
+
+	emitOpcInfoFns(&ectx, descs)
+	emitDecoderFn(&ectx, descs)
+
+	for _, f := range formats {
+		emitFmtDecoderFn(&ectx, f)
+	}
+
+	emitOperandsDecoderFn(&ectx, formats)
+
+	ectx.Emit("\n#endif /* %s */\n", guard)
+
+	return ectx.Finalize()
+}
+
+////////////////////////////////////////////////////////////////////////////
+
+// e.g. "insn_t" -> "la_insn_t"
+func identName(x string) string {
+	return fmt.Sprintf("%s_%s", strings.ToLower(*prefix), x)
+}
+
+// e.g. "ASSERT" -> "LA_ASSERT"
+func macroName(x string) string {
+	return fmt.Sprintf("%s_%s", strings.ToUpper(*prefix), x)
+}
+
+////////////////////////////////////////////////////////////////////////////
+
+// e.g. "amadd_db.w" -> "AMADD_DB_W"
+func insnMnemonicToUpperCase(x string) string {
+	return strings.ToUpper(strings.ReplaceAll(x, ".", "_"))
+}
+
+func insnMnemonicToEnumVariantName(x string) string {
+	return macroName("OPC_" + insnMnemonicToUpperCase(x))
+}
+
+func fmtEnumVariantName(f *common.InsnFormat) string {
+	return macroName("FMT_" + strings.ToUpper(f.CanonicalRepr()))
+}
+
+func emitOpcEnum(ectx *common.EmitterCtx, descs []*common.InsnDescription) {
+	ectx.Emit("\ntypedef enum {\n")
+	ectx.Emit("    %s = 0,\n", macroName("OPC_INVALID"))
+
+	for _, d := range descs {
+		ectx.Emit(
+			"    %s = 0x%08x,\n",
+			insnMnemonicToEnumVariantName(d.Mnemonic),
+			d.Word,
+		)
+	}
+
+	ectx.Emit("} %s;\n", identName("opc_t"))
+}
+
+func emitFmtEnum(ectx *common.EmitterCtx, formats []*common.InsnFormat) {
+	maxOperands := 0
+
+	ectx.Emit("\ntypedef enum {\n")
+	for _, f := range formats {
+		ectx.Emit("    %s,\n", fmtEnumVariantName(f))
+
+		if len(f.Args) > maxOperands {
+			maxOperands = len(f.Args)
+		}
+	}
+	ectx.Emit("} %s;\n", identName("fmt_t"))
+
+	ectx.Emit("\n/* The maximum number of operands of any instruction.  */\n")
+	ectx.Emit("#define %s %d\n", macroName("MAX_OPERANDS"), maxOperands)
+}
+
+func argVarName(a *common.Arg) string {
+	return strings.ToLower(a.CanonicalRepr())
+}
+
+func argCType(a *common.Arg) string {
+	if a.Kind == common.ArgKindSignedImm {
+		return "int32_t"
+	}
+	return "uint32_t"
+}
+
+// e.g. "LA_FITS_SK12"
+func rangeCheckMacroNameForArg(a *common.Arg) string {
+	return macroName("FITS_" + strings.ToUpper(a.CanonicalRepr()))
+}
+
+func emitRangeCheckMacros(ectx *common.EmitterCtx, formats []*common.InsnFormat) {
+	argsSet := make(map[string]*common.Arg)
+	for _, f := range formats {
+		for _, a := range f.Args {
+			argsSet[rangeCheckMacroNameForArg(a)] = a
+		}
+	}
+
+	names := make([]string, 0, len(argsSet))
+	for name := range argsSet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ectx.Emit("\n/* Checks whether the operand value is encodable in the field.  */\n")
+
+	for _, name := range names {
+		a := argsSet[name]
+
+		switch a.Kind {
+		case common.ArgKindSignedImm:
+			// -min <= x <= max
+			max := (1 << (a.TotalWidth() - 1)) - 1
+			negativeMin := max + 1
+			ectx.Emit("#define %s(x) ((x) >= -0x%x && (x) <= 0x%x)\n", name, negativeMin, max)
+
+		default:
+			// x <= max
+			max := (1 << a.TotalWidth()) - 1
+			ectx.Emit("#define %s(x) ((x) <= 0x%x)\n", name, max)
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////
+
+func slotEncoderFnNameForSc(sc string) string {
+	plural := ""
+	if len(sc) > 1 {
+		plural = "s"
+	}
+
+	return identName(fmt.Sprintf("encode_%s_slot%s", strings.ToLower(sc), plural))
+}
+
+func emitSlotEncoderFn(ectx *common.EmitterCtx, sc string) {
+	funcName := slotEncoderFnNameForSc(sc)
+	scLower := strings.ToLower(sc)
+
+	ectx.Emit("\nstatic inline uint32_t\n%s(uint32_t opc", funcName)
+	for _, s := range scLower {
+		ectx.Emit(", uint32_t %c", s)
+	}
+	ectx.Emit(")\n{\n")
+
+	ectx.Emit("    return opc")
+
+	for _, s := range scLower {
+		offset := common.SlotOffsetForChar(s)
+
+		ectx.Emit(" | %c", s)
+		if offset > 0 {
+			ectx.Emit(" << %d", offset)
+		}
+	}
+
+	ectx.Emit(";\n}\n")
+}
+
+func fmtEncoderFnNameForInsnFormat(f *common.InsnFormat) string {
+	return identName(fmt.Sprintf("encode_%s_insn", strings.ToLower(f.CanonicalRepr())))
+}
+
+func emitFmtEncoderFn(ectx *common.EmitterCtx, f *common.InsnFormat) {
+	// EMPTY doesn't need encoder after all
+	if len(f.Args) == 0 {
+		return
+	}
+
+	ectx.Emit("\nstatic inline uint32_t\n%s(%s opc", fmtEncoderFnNameForInsnFormat(f), identName("opc_t"))
+	for _, a := range f.Args {
+		ectx.Emit(", %s %s", argCType(a), argVarName(a))
+	}
+	ectx.Emit(")\n{\n")
+
+	for _, a := range f.Args {
+		ectx.Emit("    %s(%s(%s));\n", macroName("ASSERT"), rangeCheckMacroNameForArg(a), argVarName(a))
+	}
+
+	// collect slot expressions
+	slotExprs := make(map[uint]string)
+	for _, a := range f.Args {
+		varName := argVarName(a)
+
+		if len(a.Slots) == 1 {
+			if a.Kind == common.ArgKindSignedImm {
+				// signed imms need masking to convert to unsigned slot value
+				mask := (1 << a.TotalWidth()) - 1
+				slotExprs[a.Slots[0].Offset] = fmt.Sprintf("((uint32_t)%s & 0x%x)", varName, mask)
+			} else {
+				slotExprs[a.Slots[0].Offset] = varName
+			}
+			continue
+		}
+
+		// see (*common.Arg).SlotParts for how the arg is split into slots
+		for _, p := range a.SlotParts() {
+			mask := (1 << p.Slot.Width) - 1
+
+			expr := fmt.Sprintf("(uint32_t)%s", varName)
+			if p.Shift > 0 {
+				expr = fmt.Sprintf("(%s >> %d)", expr, p.Shift)
+			}
+
+			slotExprs[p.Slot.Offset] = fmt.Sprintf("(%s & 0x%x)", expr, mask)
+		}
+	}
+
+	sc := f.SlotCombination()
+	encFnName := slotEncoderFnNameForSc(sc)
+	ectx.Emit("    return %s(opc", encFnName)
+
+	for _, s := range sc {
+		offset := common.SlotOffsetForChar(s)
+		slotExpr, ok := slotExprs[offset]
+		if !ok {
+			panic("should never happen")
+		}
+		ectx.Emit(", %s", slotExpr)
+	}
+
+	ectx.Emit(");\n}\n")
+}
+
+// transform InsnDescription to syntax example, e.g. "addi.d d, j, sk12"
+func insnSyntaxDescForInsn(d *common.InsnDescription) string {
+	if len(d.Format.Args) == 0 {
+		// special-case EMPTY
+		return d.Mnemonic
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(d.Mnemonic)
+	for i, a := range d.Format.Args {
+		if i == 0 {
+			sb.WriteRune(' ')
+		} else {
+			sb.WriteString(", ")
+		}
+
+		sb.WriteString(argVarName(a))
+	}
+
+	return sb.String()
+}
+
+// e.g. "la_amadd_db_w"
+func encoderFnNameForInsn(d *common.InsnDescription) string {
+	return identName(strings.ToLower(insnMnemonicToUpperCase(d.Mnemonic)))
+}
+
+func emitEncoderForInsn(ectx *common.EmitterCtx, d *common.InsnDescription) {
+	opc := insnMnemonicToEnumVariantName(d.Mnemonic)
+
+	// docstring line
+	ectx.Emit("\n/* Encodes the `%s` instruction.  */\n", insnSyntaxDescForInsn(d))
+
+	// function header
+	ectx.Emit("static inline uint32_t\n%s(", encoderFnNameForInsn(d))
+	if len(d.Format.Args) == 0 {
+		ectx.Emit("void")
+	}
+	for i, a := range d.Format.Args {
+		if i > 0 {
+			ectx.Emit(", ")
+		}
+		ectx.Emit("%s %s", argCType(a), argVarName(a))
+	}
+	ectx.Emit(")\n{\n")
+
+	if len(d.Format.Args) == 0 {
+		// special-case EMPTY
+		ectx.Emit("    return %s;\n", opc)
+		ectx.Emit("}\n")
+		return
+	}
+
+	// body and tail
+	ectx.Emit("    return %s(%s", fmtEncoderFnNameForInsnFormat(d.Format), opc)
+	for _, a := range d.Format.Args {
+		ectx.Emit(", %s", argVarName(a))
+	}
+	ectx.Emit(");\n")
+
+	ectx.Emit("}\n")
+}
+
+////////////////////////////////////////////////////////////////////////////
+
+func emitOpcInfoFns(ectx *common.EmitterCtx, descs []*common.InsnDescription) {
+	ectx.Emit("\n/* Returns the mnemonic of the instruction, or NULL if invalid.  */\n")
+	ectx.Emit("static inline const char *\n%s(%s opc)\n{\n", identName("opc_mnemonic"), identName("opc_t"))
+	ectx.Emit("    switch (opc) {\n")
+	for _, d := range descs {
+		ectx.Emit("    case %s:\n", insnMnemonicToEnumVariantName(d.Mnemonic))
+		ectx.Emit("        return \"%s\";\n", d.Mnemonic)
+	}
+	ectx.Emit("    default:\n")
+	ectx.Emit("        return (const char *)0;\n")
+	ectx.Emit("    }\n")
+	ectx.Emit("}\n")
+
+	// group by format to keep the switch short
+	byFmt := make(map[string][]*common.InsnDescription)
+	var fmtNames []string
+	for _, d := range descs {
+		name := fmtEnumVariantName(d.Format)
+		if _, ok := byFmt[name]; !ok {
+			fmtNames = append(fmtNames, name)
+		}
+		byFmt[name] = append(byFmt[name], d)
+	}
+	sort.Strings(fmtNames)
+
+	ectx.Emit("\n/* Returns the format of the instruction, which must be valid.  */\n")
+	ectx.Emit("static inline %s\n%s(%s opc)\n{\n", identName("fmt_t"), identName("opc_fmt"), identName("opc_t"))
+	ectx.Emit("    switch (opc) {\n")
+	for i, name := range fmtNames {
+		for _, d := range byFmt[name] {
+			ectx.Emit("    case %s:\n", insnMnemonicToEnumVariantName(d.Mnemonic))
+		}
+		if i == len(fmtNames)-1 {
+			ectx.Emit("    default:\n")
+		}
+		ectx.Emit("        return %s;\n", name)
+	}
+	ectx.Emit("    }\n")
+	ectx.Emit("}\n")
+}
+
+func emitDecoderFn(ectx *common.EmitterCtx, descs []*common.InsnDescription) {
+	// most specific masks first
+	groups := common.GroupByMatchBitmask(descs)
+
+	ectx.Emit("\n/*\n")
+	ectx.Emit(" * Returns the opcode of the instruction word, or %s if no\n", macroName("OPC_INVALID"))
+	ectx.Emit(" * instruction matches.\n")
+	ectx.Emit(" */\n")
+	ectx.Emit("static inline %s\n%s(uint32_t insn)\n{\n", identName("opc_t"), identName("decode"))
+
+	for i, g := range groups {
+		if i > 0 {
+			ectx.Emit("\n")
+		}
+
+		ectx.Emit("    switch (insn & 0x%08x) {\n", g.Mask)
+		for _, d := range g.Descs {
+			opc := insnMnemonicToEnumVariantName(d.Mnemonic)
+			ectx.Emit("    case %s:\n", opc)
+			ectx.Emit("        return %s;\n", opc)
+		}
+		ectx.Emit("    }\n")
+	}
+
+	ectx.Emit("\n    return %s;\n", macroName("OPC_INVALID"))
+	ectx.Emit("}\n")
+}
+
+func fmtDecoderFnNameForInsnFormat(f *common.InsnFormat) string {
+	return identName(fmt.Sprintf("decode_%s_insn", strings.ToLower(f.CanonicalRepr())))
+}
+
+func emitFmtDecoderFn(ectx *common.EmitterCtx, f *common.InsnFormat) {
+	// EMPTY doesn't need decoder after all
+	if len(f.Args) == 0 {
+		return
+	}
+
+	ectx.Emit("\nstatic inline void\n%s(uint32_t insn", fmtDecoderFnNameForInsnFormat(f))
+	for _, a := range f.Args {
+		ectx.Emit(", %s *%s", argCType(a), argVarName(a))
+	}
+	ectx.Emit(")\n{\n")
+
+	for _, a := range f.Args {
+		// concatenate the slots, see (*common.Arg).SlotParts
+		var parts []string
+		for _, p := range a.SlotParts() {
+			expr := fmt.Sprintf("(insn >> %d & 0x%x)", p.Slot.Offset, (1<<p.Slot.Width)-1)
+			if p.Slot.Offset == 0 {
+				expr = fmt.Sprintf("(insn & 0x%x)", (1<<p.Slot.Width)-1)
+			}
+			if p.Shift > 0 {
+				expr = fmt.Sprintf("%s << %d", expr, p.Shift)
+			}
+			parts = append(parts, expr)
+		}
+		raw := strings.Join(parts, " | ")
+
+		if a.Kind != common.ArgKindSignedImm {
+			ectx.Emit("    *%s = %s;\n", argVarName(a), raw)
+			continue
+		}
+
+		// sign-extend without relying on implementation-defined conversions
+		signBit := 1 << (a.TotalWidth() - 1)
+		ectx.Emit("    {\n")
+		ectx.Emit("        uint32_t x = %s;\n", raw)
+		ectx.Emit("        *%s = (int32_t)x - (int32_t)((x & 0x%x) << 1);\n", argVarName(a), signBit)
+		ectx.Emit("    }\n")
+	}
+
+	ectx.Emit("}\n")
+}
+
+func emitOperandsDecoderFn(ectx *common.EmitterCtx, formats []*common.InsnFormat) {
+	ectx.Emit("\n/*\n")
+	ectx.Emit(" * Extracts the operands of the instruction word in canonical order, given\n")
+	ectx.Emit(" * its valid opcode. Returns the number of operands.\n")
+	ectx.Emit(" */\n")
+	ectx.Emit(
+		"static inline int\n%s(%s opc, uint32_t insn, int32_t operands[%s])\n{\n",
+		identName("decode_operands"),
+		identName("opc_t"),
+		macroName("MAX_OPERANDS"),
+	)
+
+	ectx.Emit("    switch (%s(opc)) {\n", identName("opc_fmt"))
+	for _, f := range formats {
+		ectx.Emit("    case %s: {\n", fmtEnumVariantName(f))
+
+		for _, a := range f.Args {
+			ectx.Emit("        %s %s;\n", argCType(a), argVarName(a))
+		}
+
+		if len(f.Args) > 0 {
+			ectx.Emit("        %s(insn", fmtDecoderFnNameForInsnFormat(f))
+			for _, a := range f.Args {
+				ectx.Emit(", &%s", argVarName(a))
+			}
+			ectx.Emit(");\n")
+		}
+
+		for i, a := range f.Args {
+			ectx.Emit("        operands[%d] = (int32_t)%s;\n", i, argVarName(a))
+		}
+
+		ectx.Emit("        return %d;\n", len(f.Args))
+		ectx.Emit("    }\n")
+	}
+	ectx.Emit("    }\n")
+
+	ectx.Emit("\n    return 0;\n")
+	ectx.Emit("}\n")
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+)
+
+// extremeOperands returns the operands of the insn all at their minimum, and
+// all at their maximum, without postprocessing.
+func extremeOperands(d *common.InsnDescription) [][]int64 {
+	mins := make([]int64, len(d.Format.Args))
+	maxs := make([]int64, len(d.Format.Args))
+	for i, a := range d.Format.Args {
+		if !a.Kind.IsImm() {
+			maxs[i] = int64(a.Kind.RegClass().Count() - 1)
+			continue
+		}
+
+		width := a.TotalWidth()
+		if a.Kind == common.ArgKindSignedImm {
+			mins[i] = -(1 << (width - 1))
+			maxs[i] = 1<<(width-1) - 1
+		} else {
+			maxs[i] = 1<<width - 1
+		}
+	}
+	return [][]int64{mins, maxs}
+}
+
+// TestRoundTrip compiles and runs a C99 program checking the generated
+// encoders and decoders against common for all insns, at the extremes of
+// their operands.
+func TestRoundTrip(t *testing.T) {
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("cc not found")
+	}
+
+	// tests are run in the package directory, one level below the commands
+	descs, err := common.ReadAllInsnDescs(filepath.Join("..", common.TablesDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "insns.h"), generate(descs), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("#include <stdio.h>\n")
+	sb.WriteString("#include \"insns.h\"\n")
+	sb.WriteString("\nstatic int failures;\n")
+	sb.WriteString("\nstatic void check(int ok, const char *what)\n{\n")
+	sb.WriteString("    if (!ok) {\n")
+	sb.WriteString("        fprintf(stderr, \"failed: %s\\n\", what);\n")
+	sb.WriteString("        failures++;\n")
+	sb.WriteString("    }\n")
+	sb.WriteString("}\n")
+	sb.WriteString("\nint main(void)\n{\n")
+	fmt.Fprintf(&sb, "    int32_t ops[%s];\n", macroName("MAX_OPERANDS"))
+	for _, d := range descs {
+		opc := insnMnemonicToEnumVariantName(d.Mnemonic)
+
+		for _, operands := range extremeOperands(d) {
+			word, err := d.Format.Encode(d.Word, operands)
+			if err != nil {
+				t.Fatalf("%s: %v", d.Mnemonic, err)
+			}
+
+			args := make([]string, len(operands))
+			for i, val := range operands {
+				args[i] = fmt.Sprintf("%d", val)
+			}
+			what := fmt.Sprintf("%s %v", d.Mnemonic, operands)
+
+			fmt.Fprintf(
+				&sb,
+				"    check(%s(%s) == 0x%08xu, \"encoding %s\");\n",
+				encoderFnNameForInsn(d),
+				strings.Join(args, ", "),
+				word,
+				what,
+			)
+			fmt.Fprintf(
+				&sb,
+				"    check(%s(0x%08xu) == %s, \"decoding %s\");\n",
+				identName("decode"),
+				word,
+				opc,
+				what,
+			)
+			fmt.Fprintf(
+				&sb,
+				"    check(%s(%s, 0x%08xu, ops) == %d, \"decoding operands of %s\");\n",
+				identName("decode_operands"),
+				opc,
+				word,
+				len(operands),
+				what,
+			)
+			for i, val := range operands {
+				fmt.Fprintf(
+					&sb,
+					"    check(ops[%d] == %d, \"decoding operand %d of %s\");\n",
+					i,
+					val,
+					i,
+					what,
+				)
+			}
+		}
+	}
+	sb.WriteString("\n    return failures != 0;\n")
+	sb.WriteString("}\n")
+
+	src := filepath.Join(dir, "roundtrip.c")
+	bin := filepath.Join(dir, "roundtrip")
+	if err := os.WriteFile(src, []byte(sb.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command(cc, "-std=c99", "-Wall", "-Werror", "-o", bin, src).CombinedOutput()
+	if err != nil {
+		t.Fatalf("cc failed: %v\n%s", err, out)
+	}
+
+	out, err = exec.Command(bin).CombinedOutput()
+	if err != nil {
+		t.Fatalf("round trip failed: %v\n%s", err, out)
+	}
+}
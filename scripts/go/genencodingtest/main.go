@@ -2,18 +2,46 @@ package main
 
 import (
 	"crypto/sha256"
+	"flag"
 	"fmt"
 	"math/rand"
 	"os"
 	"sort"
 
 	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/registers"
 )
 
+var selector = flag.String(
+	"select",
+	"",
+	"only process insns matching this selector, e.g. \"la64,base,lsx\"",
+)
+
+var boundary = flag.Bool(
+	"boundary",
+	false,
+	"also emit test cases with boundary values for every arg",
+)
+
+var exhaustive = flag.Bool(
+	"exhaustive",
+	false,
+	"also emit test cases sweeping every register arg over all registers",
+)
+
+var maxFiller = flag.Int(
+	"max-filler",
+	1<<16,
+	"maximum number of insns to put between a branch and its target in -boundary mode",
+)
+
+// Takes the insn description files to process as arguments, defaulting to
+// all of them.
 func main() {
-	inputs := os.Args[1:]
+	flag.Parse()
 
-	descs, err := common.ReadInsnDescs(inputs)
+	descs, err := common.ReadSelectedInsnDescs(flag.Args(), *selector)
 	if err != nil {
 		panic(err)
 	}
@@ -27,36 +55,44 @@ func main() {
 	}
 
 	for _, d := range descs {
-		// test cases for jumps are to be manually written so skip those too
-		switch d.Mnemonic {
-		case "beqz", "bnez", "bceqz", "bcnez",
-			"jirl", "b", "bl",
-			"beq", "bne", "bgt", "ble", "bgtu", "bleu":
+		if d.BranchTargetIndex() >= 0 {
+			emitBranchTestCases(&tp, d)
 			continue
 		}
 
-		tc := generateTestCase(d)
+		var tcs []testcaseData
+		if d.Mnemonic == "jirl" {
+			tcs = generateJirlTestCases(d)
+		} else {
+			tcs = generateTestCases(d)
+		}
 
-		tp.oneTab()
-		tp.printf("%s", tc.mnemonic)
-		tp.tabUntil(32)
+		for _, tc := range tcs {
+			printTestCase(&tp, tc)
+		}
+	}
+}
 
-		// Go assembly has arguments in reverse order.
-		for i := len(tc.args) - 1; i >= 0; i-- {
-			tca := tc.args[i]
+func printTestCase(tp *tabPrinter, tc testcaseData) {
+	tp.oneTab()
+	tp.printf("%s", tc.mnemonic)
+	tp.tabUntil(32)
 
-			sep := ""
-			if i < len(tc.args)-1 {
-				sep = ", "
-			}
+	// Go assembly has arguments in reverse order.
+	for i := len(tc.args) - 1; i >= 0; i-- {
+		tca := tc.args[i]
 
-			tp.printf("%s%s", sep, tca.repr)
+		sep := ""
+		if i < len(tc.args)-1 {
+			sep = ", "
 		}
 
-		tp.tabUntil(64)
-		fmt.Printf("// %s", formatExpectedInsnWord(tc.expectedInsnWord))
-		tp.newline()
+		tp.printf("%s%s", sep, tca.repr)
 	}
+
+	tp.tabUntil(64)
+	fmt.Printf("// %s", formatExpectedInsnWord(tc.expectedInsnWord))
+	tp.newline()
 }
 
 ////////////////////////////////////////////////////////////////////////////
@@ -107,52 +143,70 @@ type testcaseData struct {
 }
 
 type testcaseArg struct {
-	val  uint32
+	val  int64
 	repr string
 }
 
-func generateTestCase(d *common.InsnDescription) testcaseData {
-	rng := rngFromInsnDescription(d)
+// generateTestCases returns the test cases for the insn: the random one,
+// followed by the boundary-value and exhaustive ones if requested.
+//
+// The latter are variations of the random case with one arg changed, and
+// cases encoding to the same word as an earlier one are dropped.
+func generateTestCases(d *common.InsnDescription) []testcaseData {
+	base := randomOperands(d, rngFromInsnDescription(d))
+
+	var result []testcaseData
+	seen := make(map[uint32]bool)
+	add := func(operands []int64) {
+		tc := makeTestCase(d, operands)
+		if seen[tc.expectedInsnWord] {
+			return
+		}
+		seen[tc.expectedInsnWord] = true
+		result = append(result, tc)
+	}
 
-	args := make([]testcaseArg, len(d.Format.Args))
-	for i, a := range d.Format.Args {
-		var generatedArg testcaseArg
-		switch a.Kind {
-		case common.ArgKindIntReg:
-			// remove R0, R21 (reserved) and R31 (g)
-			val := uint32(rng.Intn(29)) + 1
-			if val >= 21 {
-				val++
-			}
+	variate := func(i int, val int64) {
+		operands := make([]int64, len(base))
+		copy(operands, base)
+		operands[i] = val
+		add(operands)
+	}
 
-			var repr string
-			switch val {
-			case 3:
-				repr = "SP"
-			default:
-				repr = fmt.Sprintf("R%d", val)
-			}
+	add(base)
 
-			generatedArg = testcaseArg{
-				val:  val,
-				repr: repr,
+	if *boundary {
+		for i, a := range d.Format.Args {
+			for _, val := range boundaryValues(a) {
+				variate(i, val)
 			}
+		}
+	}
 
-		case common.ArgKindFPReg:
-			// remove F0
-			val := uint32(rng.Intn(31)) + 1
-			generatedArg = testcaseArg{
-				val:  val,
-				repr: fmt.Sprintf("F%d", val),
+	if *exhaustive {
+		for i, a := range d.Format.Args {
+			if a.Kind.IsImm() {
+				continue
 			}
-
-		case common.ArgKindFCCReg:
-			// remove FCC0
-			val := uint32(rng.Intn(7)) + 1
-			generatedArg = testcaseArg{
-				val:  val,
-				repr: fmt.Sprintf("FCC%d", val),
+			for idx := 0; idx < a.Kind.RegClass().Count(); idx++ {
+				variate(i, int64(idx))
 			}
+		}
+	}
+
+	return result
+}
+
+// randomOperands returns random operands for the insn, in canonical order
+// and without postprocessing.
+func randomOperands(d *common.InsnDescription, rng *rand.Rand) []int64 {
+	operands := make([]int64, len(d.Format.Args))
+	for i, a := range d.Format.Args {
+		switch a.Kind {
+		case common.ArgKindIntReg, common.ArgKindFPReg, common.ArgKindFCCReg,
+			common.ArgKindScratchReg, common.ArgKindVReg, common.ArgKindXReg:
+			regs := usableRegs(a.Kind.RegClass())
+			operands[i] = int64(regs[rng.Intn(len(regs))].Index)
 
 		case common.ArgKindSignedImm, common.ArgKindUnsignedImm:
 			valueRange := int64(1) << a.TotalWidth()
@@ -170,27 +224,72 @@ func generateTestCase(d *common.InsnDescription) testcaseData {
 				val = lowerBound + rng.Int63n(valueRange)
 			}
 
-			generatedArg = testcaseArg{
-				val:  uint32(val),
-				repr: fmt.Sprintf("$%d", val),
-			}
+			operands[i] = val
+		}
+	}
+
+	return operands
+}
+
+// boundaryValues returns the values of the arg most likely to reveal
+// off-by-one encoding bugs.
+//
+// For registers these are the first and last register of the class, e.g. R0
+// and R31, or FCC0 and FCC7. For immediates these are the minimum, maximum
+// and -1, along with the values on either side of every slot split point,
+// so that a wrongly shifted slot is caught.
+func boundaryValues(a *common.Arg) []int64 {
+	if !a.Kind.IsImm() {
+		return []int64{0, int64(a.Kind.RegClass().Count() - 1)}
+	}
+
+	min, max := a.ValueRange()
+	candidates := []int64{min, max}
+	if a.Kind == common.ArgKindSignedImm {
+		candidates = append(candidates, -1)
+	}
+
+	for _, p := range a.SlotParts() {
+		if p.Shift == 0 {
+			continue
 		}
 
-		args[i] = generatedArg
+		split := int64(1) << p.Shift
+		candidates = append(candidates, split-1, split)
+		if a.Kind == common.ArgKindSignedImm {
+			candidates = append(candidates, -split, -split-1)
+		}
 	}
 
-	expectedInsnWord := d.Word
-	for i, tca := range args {
-		a := d.Format.Args[i]
+	var result []int64
+	for _, val := range candidates {
+		if val >= min && val <= max {
+			result = append(result, val)
+		}
+	}
+	return result
+}
+
+func makeTestCase(d *common.InsnDescription, operands []int64) testcaseData {
+	expectedInsnWord, err := d.Format.Encode(d.Word, operands)
+	if err != nil {
+		panic(err)
+	}
 
-		remainingBits := a.TotalWidth()
-		for _, s := range a.Slots {
-			remainingBits -= s.Width
+	args := make([]testcaseArg, len(operands))
+	for i, a := range d.Format.Args {
+		val := operands[i]
 
-			slotWidthMask := (uint32(1) << s.Width) - 1
-			slotVal := (tca.val >> remainingBits) & slotWidthMask
+		var repr string
+		if a.Kind.IsImm() {
+			repr = fmt.Sprintf("$%d", val)
+		} else {
+			repr = a.Kind.RegClass().Reg(int(val)).GoName()
+		}
 
-			expectedInsnWord |= slotVal << s.Offset
+		args[i] = testcaseArg{
+			val:  val,
+			repr: repr,
 		}
 	}
 
@@ -206,6 +305,223 @@ func generateTestCase(d *common.InsnDescription) testcaseData {
 	}
 }
 
+// the encoding of NOOP, i.e. andi $zero, $zero, 0
+const noopInsnWord = 0x03400000
+
+// generateJirlTestCases returns the test cases for jirl, whose offset is
+// given in bytes as in Go assembly, unlike the immediates of other insns.
+func generateJirlTestCases(d *common.InsnDescription) []testcaseData {
+	f := d.PostprocessedFormat()
+	offsetArg := f.Args[len(f.Args)-1]
+
+	operands := randomOperands(d, rngFromInsnDescription(d))
+	offsets := []int64{offsetArg.Post.Apply(operands[len(operands)-1])}
+	if *boundary {
+		min, max := offsetArg.ValueRange()
+		offsets = append(offsets, min, max)
+	}
+
+	result := make([]testcaseData, len(offsets))
+	for i, offset := range offsets {
+		operands[len(operands)-1] = offset
+
+		expectedInsnWord, err := f.Encode(d.Word, operands)
+		if err != nil {
+			panic(err)
+		}
+
+		result[i] = testcaseData{
+			mnemonic: common.GoAnameForInsn(d.Mnemonic)[1:],
+			args: []testcaseArg{
+				regArg(registers.ClassGPR, operands[0]),
+				regArg(registers.ClassGPR, operands[1]),
+				{val: offset, repr: fmt.Sprintf("$%d", offset)},
+			},
+			expectedInsnWord: expectedInsnWord,
+		}
+	}
+
+	return result
+}
+
+// goBranchMnemonics maps the branch insns to their spelling in Go assembly,
+// which differs from the canonical mnemonic for most of them.
+var goBranchMnemonics = map[string]string{
+	"beqz":  "BEQ", // with a single register
+	"bnez":  "BNE", // with a single register
+	"bceqz": "BFPF",
+	"bcnez": "BFPT",
+	"b":     "JMP",
+	"bl":    "JAL",
+	"beq":   "BEQ",
+	"bne":   "BNE",
+	"bgt":   "BLT", // operands swapped, as in the binutils blt
+	"ble":   "BGE",
+	"bgtu":  "BLTU",
+	"bleu":  "BGEU",
+}
+
+// emitBranchTestCases emits test cases for the PC-relative branch insn,
+// each branching to a label placed the computed number of insns away, with
+// NOOPs in between.
+//
+// A short backward and a short forward branch are always emitted. In
+// -boundary mode, branches of the maximum backward and forward reach are
+// emitted too, unless more than -max-filler NOOPs are needed, in which case
+// a comment takes the place of the case and a warning is printed to stderr.
+func emitBranchTestCases(tp *tabPrinter, d *common.InsnDescription) {
+	f := d.PostprocessedFormat()
+
+	targetIdx := d.BranchTargetIndex()
+	if targetIdx != len(f.Args)-1 {
+		panic(fmt.Sprintf("%s: branch offset is not the last arg", d.Mnemonic))
+	}
+	offsetArg := f.Args[targetIdx]
+	insnBytes := int64(1) << offsetArg.Post.Amount
+
+	mnemonic, ok := goBranchMnemonics[d.Mnemonic]
+	if !ok {
+		panic(fmt.Sprintf("%s: Go assembly spelling of branch insn unknown", d.Mnemonic))
+	}
+
+	rng := rngFromInsnDescription(d)
+	operands := randomOperands(d, rng)
+
+	// distances in insns
+	distances := []int64{
+		-1 - rng.Int63n(16),
+		1 + rng.Int63n(16),
+	}
+	if *boundary {
+		min, max := offsetArg.ValueRange()
+		for _, dist := range []int64{min / insnBytes, max / insnBytes} {
+			if n := numFillerInsns(dist); n > int64(*maxFiller) {
+				tp.printf("// %s: skipped branch of distance %d, needing %d > -max-filler NOOPs", d.Mnemonic, dist, n)
+				tp.newline()
+				fmt.Fprintf(
+					os.Stderr,
+					"warning: %s: skipped branch of distance %d, needing %d > %d NOOPs\n",
+					d.Mnemonic,
+					dist,
+					n,
+					*maxFiller,
+				)
+				continue
+			}
+			distances = append(distances, dist)
+		}
+	}
+
+	for _, dist := range distances {
+		operands[len(operands)-1] = dist * insnBytes
+		expectedInsnWord, err := f.Encode(d.Word, operands)
+		if err != nil {
+			panic(err)
+		}
+
+		var label string
+		if dist < 0 {
+			label = fmt.Sprintf("%s_back_%d", d.Mnemonic, -dist)
+		} else {
+			label = fmt.Sprintf("%s_fwd_%d", d.Mnemonic, dist)
+		}
+
+		// The registers are reversed by printTestCase like for all other
+		// insns, so that e.g. the rj of beq comes first, but the target must
+		// come last, so it goes first here.
+		args := make([]testcaseArg, len(f.Args))
+		args[0] = testcaseArg{val: dist * insnBytes, repr: label}
+		for i, a := range f.Args[:len(f.Args)-1] {
+			args[i+1] = regArg(a.Kind.RegClass(), operands[i])
+		}
+
+		tc := testcaseData{
+			mnemonic:         mnemonic,
+			args:             args,
+			expectedInsnWord: expectedInsnWord,
+		}
+
+		if dist <= 0 {
+			emitLoopAlign(tp)
+			emitLabel(tp, label)
+			emitNoops(tp, numFillerInsns(dist))
+			printTestCase(tp, tc)
+		} else {
+			printTestCase(tp, tc)
+			emitNoops(tp, numFillerInsns(dist))
+			emitLabel(tp, label)
+		}
+	}
+}
+
+// numFillerInsns returns the number of insns between a branch and its
+// target dist insns away.
+func numFillerInsns(dist int64) int64 {
+	if dist <= 0 {
+		return -dist
+	}
+	return dist - 1
+}
+
+// the alignment the Go assembler pads targets of backward branches to
+const loopAlign = 16
+
+// emitLoopAlign aligns the following target of a backward branch explicitly.
+// Otherwise the Go assembler treats it as a loop head and inserts padding
+// right before it, after the test case preceding it.
+func emitLoopAlign(tp *tabPrinter) {
+	tp.oneTab()
+	tp.printf("PCALIGN")
+	tp.tabUntil(32)
+	tp.printf("$%d", loopAlign)
+	tp.newline()
+}
+
+func emitLabel(tp *tabPrinter, label string) {
+	tp.printf("%s:", label)
+	tp.newline()
+}
+
+func emitNoops(tp *tabPrinter, n int64) {
+	for i := int64(0); i < n; i++ {
+		printTestCase(tp, testcaseData{
+			mnemonic:         "NOOP",
+			expectedInsnWord: noopInsnWord,
+		})
+	}
+}
+
+func regArg(c registers.Class, idx int64) testcaseArg {
+	return testcaseArg{
+		val:  idx,
+		repr: c.Reg(int(idx)).GoName(),
+	}
+}
+
+// usableRegs returns the registers of the class usable in the random test
+// cases.
+//
+// Register 0 is avoided so that the slot encoding is actually exercised,
+// along with registers reserved by the psABI. R31 is left out too, so that
+// the random cases stay the same as before; the -boundary and -exhaustive
+// modes cover the whole class.
+func usableRegs(c registers.Class) []registers.Register {
+	var result []registers.Register
+	for i := 1; i < c.Count(); i++ {
+		r := c.Reg(i)
+		if r.IsReserved() {
+			continue
+		}
+
+		if c == registers.ClassGPR && i == 31 {
+			continue
+		}
+
+		result = append(result, r)
+	}
+	return result
+}
+
 func rngFromInsnDescription(d *common.InsnDescription) *rand.Rand {
 	// hash the mnemonic for random seed
 	// the first few bytes are enough
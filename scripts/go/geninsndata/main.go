@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"sort"
@@ -10,25 +11,41 @@ import (
 	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
 )
 
+var selector = flag.String(
+	"select",
+	"",
+	"only process insns matching this selector, e.g. \"la64,base,lsx\"",
+)
+
+// Takes the insn description files to process as arguments, defaulting to
+// all of them.
+//
+// The generated code relies on the package it is placed in for these
+// helpers, where K is one of Int, FP, FCC, Scratch, V and X:
+//
+//   - want<K>Reg(as, reg) error, reporting whether reg is a register of the
+//     kind, as required by an operand of the insn as
+//   - reg<K>(reg) uint32, returning the index of the register of the kind
+//   - wantSignedImm(as, imm, width) and wantUnsignedImm(as, imm, width)
+//     error, reporting whether imm fits in width bits
+//
+// The Scratch, V and X helpers are only needed for LBT, LSX and LASX insns,
+// which can be left out with e.g. -select '!lbt,!lsx,!lasx'.
 func main() {
-	inputs := os.Args[1:]
+	flag.Parse()
 
-	descs, err := common.ReadInsnDescs(inputs)
+	descs, err := common.ReadSelectedInsnDescs(flag.Args(), *selector)
 	if err != nil {
 		panic(err)
 	}
 
-	formats := gatherFormats(descs)
-	scs := gatherDistinctSlotCombinations(formats)
+	formats := common.GatherFormats(descs)
+	scs := common.GatherSlotCombinations(formats)
 
 	sort.Slice(descs, func(i int, j int) bool {
 		return descs[i].Word < descs[j].Word
 	})
 
-	sort.Slice(formats, func(i int, j int) bool {
-		return formats[i].CanonicalRepr() < formats[j].CanonicalRepr()
-	})
-
 	var ectx common.EmitterCtx
 
 	ectx.Emit("// Code generated by geninsndata from loongson-community/loongarch-opcodes; DO NOT EDIT.\n\n")
@@ -52,99 +69,13 @@ func main() {
 
 ////////////////////////////////////////////////////////////////////////////
 
-func gatherFormats(descs []*common.InsnDescription) []*common.InsnFormat {
-	formatsSet := make(map[string]*common.InsnFormat)
-	for _, d := range descs {
-		canonicalFormatName := d.Format.CanonicalRepr()
-		if _, ok := formatsSet[canonicalFormatName]; !ok {
-			formatsSet[canonicalFormatName] = d.Format
-		}
-	}
-
-	result := make([]*common.InsnFormat, 0, len(formatsSet))
-	for _, f := range formatsSet {
-		result = append(result, f)
-	}
-
-	return result
-}
-
 const (
 	slotD = 0
 	slotJ = 5
 	slotK = 10
 	slotA = 15
-	slotM = 16
 )
 
-func gatherDistinctSlotCombinations(fmts []*common.InsnFormat) []string {
-	slotCombinationsSet := make(map[string]struct{})
-	for _, f := range fmts {
-		// skip EMPTY
-		if len(f.Args) == 0 {
-			continue
-		}
-		slotCombinationsSet[slotCombinationForFmt(f)] = struct{}{}
-	}
-
-	result := make([]string, 0, len(slotCombinationsSet))
-	for sc := range slotCombinationsSet {
-		result = append(result, sc)
-	}
-	sort.Strings(result)
-
-	return result
-}
-
-// slot combination looks like "DJKM"
-func slotCombinationForFmt(f *common.InsnFormat) string {
-
-	var slots []int
-	for _, a := range f.Args {
-		for _, s := range a.Slots {
-			slots = append(slots, int(s.Offset))
-		}
-	}
-	sort.Ints(slots)
-
-	var sb strings.Builder
-	for _, s := range slots {
-		switch s {
-		case slotD:
-			sb.WriteRune('D')
-		case slotJ:
-			sb.WriteRune('J')
-		case slotK:
-			sb.WriteRune('K')
-		case slotA:
-			sb.WriteRune('A')
-		case slotM:
-			sb.WriteRune('M')
-		default:
-			panic("should never happen")
-		}
-	}
-
-	return sb.String()
-}
-
-func slotOffsetFromRune(s rune) int {
-	switch s {
-	case 'D', 'd':
-		return slotD
-	case 'J', 'j':
-		return slotJ
-	case 'K', 'k':
-		return slotK
-	case 'A', 'a':
-		return slotA
-	case 'M', 'm':
-		return slotM
-	default:
-		panic("should never happen")
-	}
-}
-
 ////////////////////////////////////////////////////////////////////////////
 
 ////////////////////////////////////////////////////////////////////////////
@@ -176,6 +107,9 @@ func emitInsnFormatArityFn(
 	ectx.Emit("\tswitch f {\n")
 	for arity := 0; arity < 5; arity++ {
 		cases := arityMap[arity]
+		if len(cases) == 0 {
+			continue
+		}
 
 		ectx.Emit("\tcase ")
 		for i, f := range cases {
@@ -287,6 +221,15 @@ func emitValidatorForFormat(ectx *common.EmitterCtx, f *common.InsnFormat) {
 		case common.ArgKindFCCReg:
 			ectx.Emit("wantFCCReg(insn.as, %s)", argParamName)
 
+		case common.ArgKindScratchReg:
+			ectx.Emit("wantScratchReg(insn.as, %s)", argParamName)
+
+		case common.ArgKindVReg:
+			ectx.Emit("wantVReg(insn.as, %s)", argParamName)
+
+		case common.ArgKindXReg:
+			ectx.Emit("wantXReg(insn.as, %s)", argParamName)
+
 		case common.ArgKindSignedImm,
 			common.ArgKindUnsignedImm:
 			// want[Un]signedImm(argX, width)
@@ -298,6 +241,9 @@ func emitValidatorForFormat(ectx *common.EmitterCtx, f *common.InsnFormat) {
 			}
 
 			ectx.Emit("%s(insn.as, %s, %d)", wantFuncName, argParamName, a.TotalWidth())
+
+		default:
+			panic("unreachable")
 		}
 
 		ectx.Emit("; err != nil {\n\t\treturn err\n\t}\n")
@@ -334,7 +280,7 @@ func emitSlotEncoderFn(ectx *common.EmitterCtx, sc string) {
 	ectx.Emit("return bits")
 
 	for _, s := range scLower {
-		offset := slotOffsetFromRune(s)
+		offset := common.SlotOffsetForChar(s)
 
 		ectx.Emit(" | %c", s)
 		if offset > 0 {
@@ -385,6 +331,12 @@ func emitBigEncoderFn(ectx *common.EmitterCtx, fmts []*common.InsnFormat) {
 				ectx.Emit("regFP(%s)", fieldExpr)
 			case common.ArgKindFCCReg:
 				ectx.Emit("regFCC(%s)", fieldExpr)
+			case common.ArgKindScratchReg:
+				ectx.Emit("regScratch(%s)", fieldExpr)
+			case common.ArgKindVReg:
+				ectx.Emit("regV(%s)", fieldExpr)
+			case common.ArgKindXReg:
+				ectx.Emit("regX(%s)", fieldExpr)
 			case common.ArgKindSignedImm, common.ArgKindUnsignedImm:
 				widthMask := (1 << a.TotalWidth()) - 1
 				ectx.Emit("uint32(%s) & 0x%x", fieldExpr, widthMask)
@@ -403,51 +355,32 @@ func emitBigEncoderFn(ectx *common.EmitterCtx, fmts []*common.InsnFormat) {
 			if len(a.Slots) == 1 {
 				slotExprs[a.Slots[0].Offset] = argVarName
 			} else {
-				// remainingBits is shift amount to extract the current slot from arg
-				//
-				// take example of Sd5k16:
-				//
-				// Sd5k16 = (MSB) DDDDDKKKKKKKKKKKKKKKK (LSB)
-				//
-				// initially remainingBits = 5+16
-				//
-				// consume from left to right:
-				//
-				// slot d5: remainingBits = 16
-				// thus d5 = (sd5k16 >> 16) & 0b11111
-				// emit (d5 expr above)
-				//
-				// slot k16: remainingBits = 0
-				// thus k16 = (sd5k16 >> 0) & 0b1111111111111111
-				//          = sd5k16 & 0b1111111111111111
-				// emit (k16 expr above)
-				remainingBits := int(a.TotalWidth())
-				for _, s := range a.Slots {
-					remainingBits -= int(s.Width)
-					mask := int((1 << s.Width) - 1)
+				// see (*common.Arg).SlotParts for how the arg is split into slots
+				for _, p := range a.SlotParts() {
+					mask := int((1 << p.Slot.Width) - 1)
 
 					var sb strings.Builder
 					sb.WriteString(argVarName)
 
-					if remainingBits > 0 {
+					if p.Shift > 0 {
 						sb.WriteString(">>")
-						sb.WriteString(strconv.Itoa(remainingBits))
+						sb.WriteString(strconv.Itoa(int(p.Shift)))
 					}
 
 					sb.WriteString("&0x")
 					sb.WriteString(strconv.FormatUint(uint64(mask), 16))
 
-					slotExprs[s.Offset] = sb.String()
+					slotExprs[p.Slot.Offset] = sb.String()
 				}
 			}
 		}
 
-		sc := slotCombinationForFmt(f)
+		sc := f.SlotCombination()
 		encFnName := slotEncoderFnNameForSc(sc)
 		ectx.Emit("return %s(enc.bits", encFnName)
 
 		for _, s := range sc {
-			offset := uint(slotOffsetFromRune(s))
+			offset := common.SlotOffsetForChar(s)
 			slotExpr, ok := slotExprs[offset]
 			if !ok {
 				panic("should never happen")
@@ -99,6 +99,33 @@ func prepareScope(pkg *gox.Package) {
 		false,
 	)
 
+	// func wantScratchReg(uint32) error
+	pkg.NewFunc(
+		nil,
+		"wantScratchReg",
+		gox.NewTuple(pkg.NewParam("", tyUint32)),
+		gox.NewTuple(pkg.NewParam("", tyError)),
+		false,
+	)
+
+	// func wantVReg(uint32) error
+	pkg.NewFunc(
+		nil,
+		"wantVReg",
+		gox.NewTuple(pkg.NewParam("", tyUint32)),
+		gox.NewTuple(pkg.NewParam("", tyError)),
+		false,
+	)
+
+	// func wantXReg(uint32) error
+	pkg.NewFunc(
+		nil,
+		"wantXReg",
+		gox.NewTuple(pkg.NewParam("", tyUint32)),
+		gox.NewTuple(pkg.NewParam("", tyError)),
+		false,
+	)
+
 	// func wantSignedImm(uint32, int) error
 	pkg.NewFunc(
 		nil,
@@ -170,6 +197,24 @@ func emitValidatorForFormat(pkg *gox.Package, f *common.InsnFormat) {
 				Val(argParam).
 				Call(1)
 
+		case common.ArgKindScratchReg:
+			// wantScratchReg(argX)
+			bldr = bldr.Val(pkg.Ref("wantScratchReg")).
+				Val(argParam).
+				Call(1)
+
+		case common.ArgKindVReg:
+			// wantVReg(argX)
+			bldr = bldr.Val(pkg.Ref("wantVReg")).
+				Val(argParam).
+				Call(1)
+
+		case common.ArgKindXReg:
+			// wantXReg(argX)
+			bldr = bldr.Val(pkg.Ref("wantXReg")).
+				Val(argParam).
+				Call(1)
+
 		case common.ArgKindSignedImm,
 			common.ArgKindUnsignedImm:
 			// want[Un]signedImm(argX, width)
@@ -0,0 +1,209 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+)
+
+// schema is the JSON Schema describing the output.
+//
+//go:embed schema.json
+var schema []byte
+
+var (
+	printSchema = flag.Bool("schema", false, "print the JSON Schema of the output instead")
+	selector    = flag.String(
+		"select",
+		"",
+		"only process insns matching this selector, e.g. \"la64,base,lsx\"",
+	)
+)
+
+// Takes the insn description files to process as arguments, defaulting to
+// all of them.
+func main() {
+	flag.Parse()
+
+	if *printSchema {
+		os.Stdout.Write(schema)
+		return
+	}
+
+	descs, err := common.ReadSelectedInsnDescs(flag.Args(), *selector)
+	if err != nil {
+		panic(err)
+	}
+
+	sort.SliceStable(descs, func(i int, j int) bool {
+		return descs[i].Word < descs[j].Word
+	})
+
+	db := database{
+		Insns: make([]*insn, len(descs)),
+	}
+	for i, d := range descs {
+		// the schema only allows known extensions
+		if d.Ext == common.ExtUnknown {
+			panic(fmt.Sprintf("%s: unrecognized table file name for insn %s", d.Source, d.Mnemonic))
+		}
+
+		db.Insns[i] = convertInsn(d)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(&db)
+	if err != nil {
+		panic(err)
+	}
+}
+
+type database struct {
+	Insns []*insn `json:"insns"`
+}
+
+type insn struct {
+	Word           uint32     `json:"word"`
+	MatchMask      uint32     `json:"match_mask"`
+	Mnemonic       string     `json:"mnemonic"`
+	Format         string     `json:"format"`
+	Operands       []*operand `json:"operands"`
+	OrigFormat     *string    `json:"orig_format"`
+	OrigOperands   []*operand `json:"orig_operands"`
+	ManualMnemonic string     `json:"manual_mnemonic"`
+	Attribs        attribs    `json:"attribs"`
+	Source         string     `json:"source"`
+	Extension      string     `json:"extension"`
+	Width          string     `json:"width"`
+}
+
+type operand struct {
+	Kind        string       `json:"kind"`
+	Repr        string       `json:"repr"`
+	Slots       []*slot      `json:"slots"`
+	Postprocess *postprocess `json:"postprocess"`
+}
+
+type slot struct {
+	Offset uint `json:"offset"`
+	Width  uint `json:"width"`
+}
+
+type postprocess struct {
+	Op     string `json:"op"`
+	Amount int    `json:"amount"`
+}
+
+type attribs struct {
+	LA32        bool    `json:"la32"`
+	Primary     bool    `json:"primary"`
+	QEMU        bool    `json:"qemu"`
+	LBT         bool    `json:"lbt"`
+	LVZ         bool    `json:"lvz"`
+	Provisional bool    `json:"provisional"`
+	OrigName    *string `json:"orig_name"`
+	Rev         *string `json:"rev"`
+}
+
+func convertInsn(d *common.InsnDescription) *insn {
+	result := insn{
+		Word:           d.Word,
+		MatchMask:      d.Format.MatchBitmask(),
+		Mnemonic:       d.Mnemonic,
+		Format:         d.Format.CanonicalRepr(),
+		Operands:       convertOperands(d.PostprocessedFormat()),
+		ManualMnemonic: d.ManualMnemonic(),
+		Attribs: attribs{
+			LA32:        d.Attribs.LA32,
+			Primary:     d.Attribs.Primary,
+			QEMU:        d.Attribs.QEMU,
+			LBT:         d.Attribs.LBT,
+			LVZ:         d.Attribs.LVZ,
+			Provisional: d.Attribs.Provisional,
+		},
+		Source:    d.Source,
+		Extension: d.Ext.String(),
+		Width:     d.Width.String(),
+	}
+
+	if d.OrigFormat != nil {
+		origFormat := d.OrigFormat.CanonicalRepr()
+		result.OrigFormat = &origFormat
+		result.OrigOperands = convertOperands(d.OrigFormat)
+	}
+
+	if d.Attribs.OrigName != "" {
+		origName := d.Attribs.OrigName
+		result.Attribs.OrigName = &origName
+	}
+
+	if !d.Attribs.Rev.IsZero() {
+		rev := d.Attribs.Rev.String()
+		result.Attribs.Rev = &rev
+	}
+
+	return &result
+}
+
+func convertOperands(f *common.InsnFormat) []*operand {
+	result := make([]*operand, len(f.Args))
+	for i, a := range f.Args {
+		slots := make([]*slot, len(a.Slots))
+		for j, s := range a.Slots {
+			slots[j] = &slot{
+				Offset: s.Offset,
+				Width:  s.Width,
+			}
+		}
+
+		result[i] = &operand{
+			Kind:        argKindName(a.Kind),
+			Repr:        a.CanonicalRepr(),
+			Slots:       slots,
+			Postprocess: convertPostprocessOp(&a.Post),
+		}
+	}
+	return result
+}
+
+func argKindName(k common.ArgKind) string {
+	switch k {
+	case common.ArgKindIntReg:
+		return "int_reg"
+	case common.ArgKindFPReg:
+		return "fp_reg"
+	case common.ArgKindFCCReg:
+		return "fcc_reg"
+	case common.ArgKindScratchReg:
+		return "scratch_reg"
+	case common.ArgKindVReg:
+		return "lsx_reg"
+	case common.ArgKindXReg:
+		return "lasx_reg"
+	case common.ArgKindSignedImm:
+		return "signed_imm"
+	case common.ArgKindUnsignedImm:
+		return "unsigned_imm"
+	default:
+		panic("unreachable")
+	}
+}
+
+func convertPostprocessOp(p *common.PostprocessOp) *postprocess {
+	switch p.Kind {
+	case common.PostprocessOpKindNone:
+		return nil
+	case common.PostprocessOpKindAdd:
+		return &postprocess{Op: "add", Amount: p.Amount}
+	case common.PostprocessOpKindShl:
+		return &postprocess{Op: "shl", Amount: p.Amount}
+	default:
+		panic("unreachable")
+	}
+}
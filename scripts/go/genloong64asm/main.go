@@ -0,0 +1,335 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+)
+
+var selector = flag.String(
+	"select",
+	"",
+	"only process insns matching this selector, e.g. \"la64,base,lsx\"",
+)
+
+// Takes the insn description files to process as arguments, defaulting to
+// all of them.
+//
+// Like in golang.org/x/arch/loong64/loong64asm, insns are named and have
+// their args ordered according to the manual syntax.
+func main() {
+	flag.Parse()
+
+	descs, err := common.ReadSelectedInsnDescs(flag.Args(), *selector)
+	if err != nil {
+		panic(err)
+	}
+
+	os.Stdout.Write(generate(descs))
+}
+
+// generate returns the Go source replacing tables.go for the insns.
+func generate(descs []*common.InsnDescription) []byte {
+	sort.Slice(descs, func(i int, j int) bool {
+		return opName(descs[i]) < opName(descs[j])
+	})
+
+	var ectx common.EmitterCtx
+
+	ectx.Emit("// Code generated by genloong64asm from loongson-community/loongarch-opcodes; DO NOT EDIT.\n\n")
+	ectx.Emit("package loong64asm\n\n")
+	ectx.Emit("// NOTE: Replace tables.go with this file. instArg values of args unknown to\n")
+	ectx.Emit("// arg.go, if any, are defined below and need handling in decodeArg.\n\n")
+
+	emitOps(&ectx, descs)
+	emitOpstr(&ectx, descs)
+	emitInstArgs(&ectx, descs)
+	emitInstFormats(&ectx, descs)
+
+	return ectx.Finalize()
+}
+
+////////////////////////////////////////////////////////////////////////////
+
+// e.g. "amswap_db.w" -> "AMSWAP_DB_W"
+func opName(d *common.InsnDescription) string {
+	return strings.ToUpper(strings.ReplaceAll(d.ManualMnemonic(), ".", "_"))
+}
+
+func emitOps(ectx *common.EmitterCtx, descs []*common.InsnDescription) {
+	seen := make(map[string]string)
+
+	ectx.Emit("const (\n")
+	ectx.Emit("\t_ Op = iota\n")
+
+	for _, d := range descs {
+		name := opName(d)
+		if prev, ok := seen[name]; ok {
+			panic(fmt.Sprintf("op name %s shared by %s and %s", name, prev, d.ManualMnemonic()))
+		}
+		seen[name] = d.ManualMnemonic()
+
+		ectx.Emit("\t%s\n", name)
+	}
+
+	ectx.Emit(")\n\n")
+}
+
+func emitOpstr(ectx *common.EmitterCtx, descs []*common.InsnDescription) {
+	ectx.Emit("var opstr = [...]string{\n")
+
+	for _, d := range descs {
+		ectx.Emit("\t%s: %q,\n", opName(d), strings.ToUpper(d.ManualMnemonic()))
+	}
+
+	ectx.Emit("}\n\n")
+}
+
+// manualArg is an arg of the manual syntax of an insn.
+type manualArg struct {
+	*common.Arg
+	// isFCSR is set for FCSR operands, which are integer registers in the
+	// manual syntax but unsigned immediates in the canonical one
+	isFCSR bool
+}
+
+func manualArgs(d *common.InsnDescription) []manualArg {
+	args := d.ManualFormat().Args
+	result := make([]manualArg, len(args))
+	for i, a := range args {
+		result[i] = manualArg{Arg: a}
+
+		if a.Kind != common.ArgKindIntReg {
+			continue
+		}
+		for _, ca := range d.Format.Args {
+			if ca.Bitmask() == a.Bitmask() && ca.Kind.IsImm() {
+				result[i].isFCSR = true
+				break
+			}
+		}
+	}
+	return result
+}
+
+// upstreamInstArgs are the instArg values defined in arg.go of loong64asm,
+// in order.
+var upstreamInstArgs = []string{
+	"arg_fd",
+	"arg_fj",
+	"arg_fk",
+	"arg_fa",
+	"arg_rd",
+	"arg_rj",
+	"arg_rk",
+	"arg_op_4_0",
+	"arg_fcsr_4_0",
+	"arg_fcsr_9_5",
+	"arg_csr_23_10",
+	"arg_cd",
+	"arg_cj",
+	"arg_ca",
+	"arg_sa2_16_15",
+	"arg_sa3_17_15",
+	"arg_code_4_0",
+	"arg_code_14_0",
+	"arg_ui5_14_10",
+	"arg_ui6_15_10",
+	"arg_ui12_21_10",
+	"arg_lsbw",
+	"arg_msbw",
+	"arg_lsbd",
+	"arg_msbd",
+	"arg_hint_4_0",
+	"arg_hint_14_0",
+	"arg_level_14_0",
+	"arg_level_17_10",
+	"arg_seq_17_10",
+	"arg_si12_21_10",
+	"arg_si14_23_10",
+	"arg_si16_25_10",
+	"arg_si20_24_5",
+	"arg_offset_20_0",
+	"arg_offset_25_0",
+	"arg_offset_15_0",
+}
+
+func isUpstreamInstArg(name string) bool {
+	for _, x := range upstreamInstArgs {
+		if x == name {
+			return true
+		}
+	}
+	return false
+}
+
+// immRoles maps manual mnemonics to the roles of their immediate args, by
+// canonical repr, for the immediates named after their role in the manual
+// and in loong64asm.
+var immRoles = map[string]map[string]string{
+	"csrxchg":    {"Uk14": "csr"},
+	"gcsrxchg":   {"Uk14": "csr"},
+	"cacop":      {"Ud5": "code"},
+	"invtlb":     {"Ud5": "op"},
+	"preld":      {"Ud5": "hint"},
+	"preldx":     {"Ud5": "hint"},
+	"dbar":       {"Ud15": "hint"},
+	"ibar":       {"Ud15": "hint"},
+	"break":      {"Ud15": "code"},
+	"dbcl":       {"Ud15": "code"},
+	"syscall":    {"Ud15": "code"},
+	"hvcl":       {"Ud15": "code"},
+	"idle":       {"Ud15": "level"},
+	"lddir":      {"Uk8": "level"},
+	"ldpte":      {"Uk8": "seq"},
+	"alsl.w":     {"Ua2pp1": "sa2"},
+	"alsl.wu":    {"Ua2pp1": "sa2"},
+	"alsl.d":     {"Ua2pp1": "sa2"},
+	"bytepick.w": {"Ua2": "sa2"},
+	"bytepick.d": {"Ua3": "sa3"},
+	// bit field bounds have no slots in the name
+	"bstrins.w":  {"Um5": "msbw", "Uk5": "lsbw"},
+	"bstrpick.w": {"Um5": "msbw", "Uk5": "lsbw"},
+	"bstrins.d":  {"Um6": "msbd", "Uk6": "lsbd"},
+	"bstrpick.d": {"Um6": "msbd", "Uk6": "lsbd"},
+}
+
+// instArgName returns the name of the instArg value describing the arg of
+// the insn, e.g. "arg_rd", "arg_fcsr_4_0" or "arg_offset_15_0".
+//
+// Names follow loong64asm: registers are named after the register kind and
+// slot, and immediates after their role in the manual if any, or their kind
+// and width otherwise, followed by their slots, MSB first. Branch offsets
+// are "offset", named after their field width.
+//
+// Postprocess ops are implied by the upstream names, and only spelled out
+// as e.g. "_lsl2" for args that have no upstream name.
+func instArgName(d *common.InsnDescription, a manualArg) string {
+	slots := func() string {
+		var sb strings.Builder
+		for _, s := range a.Slots {
+			fmt.Fprintf(&sb, "_%d_%d", s.MSB(), s.Offset)
+		}
+		return sb.String()
+	}
+
+	switch {
+	case a.isFCSR:
+		return "arg_fcsr" + slots()
+	case a.Kind == common.ArgKindIntReg:
+		return "arg_r" + strings.ToLower(a.CanonicalRepr())
+	case !a.Kind.IsImm():
+		return "arg_" + strings.ToLower(a.CanonicalRepr())
+	}
+
+	mnemonic := d.ManualMnemonic()
+	if role, ok := immRoles[mnemonic][a.CanonicalRepr()]; ok {
+		if strings.HasPrefix(mnemonic, "bstr") {
+			return "arg_" + role
+		}
+		return "arg_" + role + slots()
+	}
+
+	isBranchOffset := d.BranchTargetIndex() >= 0 || mnemonic == "jirl"
+	if isBranchOffset && a.Post.Kind == common.PostprocessOpKindShl {
+		return fmt.Sprintf("arg_offset_%d_0", a.TotalWidth()-1)
+	}
+
+	prefix := "arg_ui"
+	if a.Kind == common.ArgKindSignedImm {
+		prefix = "arg_si"
+	}
+	result := fmt.Sprintf("%s%d%s", prefix, a.TotalWidth(), slots())
+	if isUpstreamInstArg(result) {
+		return result
+	}
+
+	switch a.Post.Kind {
+	case common.PostprocessOpKindShl:
+		result += fmt.Sprintf("_lsl%d", a.Post.Amount)
+	case common.PostprocessOpKindAdd:
+		result += fmt.Sprintf("_plus%d", a.Post.Amount)
+	}
+	return result
+}
+
+// emitInstArgs emits the instArg values not defined in arg.go, numbered
+// after the upstream ones.
+func emitInstArgs(ectx *common.EmitterCtx, descs []*common.InsnDescription) {
+	argsSet := make(map[string]struct{})
+	for _, d := range descs {
+		for _, a := range manualArgs(d) {
+			name := instArgName(d, a)
+			if !isUpstreamInstArg(name) {
+				argsSet[name] = struct{}{}
+			}
+		}
+	}
+
+	if len(argsSet) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(argsSet))
+	for name := range argsSet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ectx.Emit("// instArg values of args not known to arg.go, to be handled in decodeArg.\n")
+	ectx.Emit("const (\n")
+	ectx.Emit("\t%s instArg = iota + %s + 1\n", names[0], upstreamInstArgs[len(upstreamInstArgs)-1])
+	for _, name := range names[1:] {
+		ectx.Emit("\t%s\n", name)
+	}
+	ectx.Emit(")\n\n")
+}
+
+func emitInstFormats(ectx *common.EmitterCtx, descs []*common.InsnDescription) {
+	byWord := make([]*common.InsnDescription, len(descs))
+	copy(byWord, descs)
+	sort.Slice(byWord, func(i int, j int) bool {
+		return byWord[i].Word < byWord[j].Word
+	})
+
+	// the decoder takes the first matching entry, so entries with more
+	// specific masks come first
+	var sorted []*common.InsnDescription
+	for _, g := range common.GroupByMatchBitmask(byWord) {
+		sorted = append(sorted, g.Descs...)
+	}
+
+	ectx.Emit("var instFormats = [...]instFormat{\n")
+
+	for _, d := range sorted {
+		args := manualArgs(d)
+
+		names := make([]string, len(args))
+		operands := make([]string, len(args))
+		for i, a := range args {
+			names[i] = instArgName(d, a)
+			operands[i] = strings.TrimPrefix(names[i], "arg_")
+		}
+
+		// e.g. "ADDI.D rd, rj, si12_21_10"
+		syntax := strings.ToUpper(d.ManualMnemonic())
+		if len(operands) > 0 {
+			syntax += " " + strings.Join(operands, ", ")
+		}
+		ectx.Emit("\t// %s\n", syntax)
+
+		ectx.Emit(
+			"\t{mask: 0x%08x, value: 0x%08x, op: %s, args: instArgs{%s}},\n",
+			d.Format.MatchBitmask(),
+			d.Word,
+			opName(d),
+			strings.Join(names, ", "),
+		)
+	}
+
+	ectx.Emit("}\n")
+}
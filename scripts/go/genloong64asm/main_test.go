@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+)
+
+// decodeTest is put in the loong64asm package built with the generated
+// tables, checking that the cases decode to the expected op and number of
+// args.
+const decodeTest = `
+func TestDecodeGenerated(t *testing.T) {
+	for _, c := range cases {
+		var src [4]byte
+		binary.LittleEndian.PutUint32(src[:], c.word)
+
+		inst, err := Decode(src[:])
+		if err != nil {
+			t.Errorf("%08x: %v, want %s", c.word, err, c.op)
+			continue
+		}
+		if inst.Op.String() != c.op {
+			t.Errorf("%08x: got op %s, want %s", c.word, inst.Op, c.op)
+		}
+
+		nargs := 0
+		for _, a := range inst.Args {
+			if a != nil {
+				nargs++
+			}
+		}
+		if nargs != c.nargs {
+			t.Errorf("%08x: got %d args, want %d", c.word, nargs, c.nargs)
+		}
+	}
+}
+`
+
+// extremeOperands returns the operands of the insn all at their minimum, and
+// all at their maximum, without postprocessing.
+func extremeOperands(d *common.InsnDescription) [][]int64 {
+	mins := make([]int64, len(d.Format.Args))
+	maxs := make([]int64, len(d.Format.Args))
+	for i, a := range d.Format.Args {
+		if !a.Kind.IsImm() {
+			maxs[i] = int64(a.Kind.RegClass().Count() - 1)
+			continue
+		}
+
+		width := a.TotalWidth()
+		if a.Kind == common.ArgKindSignedImm {
+			mins[i] = -(1 << (width - 1))
+			maxs[i] = 1<<(width-1) - 1
+		} else {
+			maxs[i] = 1<<width - 1
+		}
+	}
+	return [][]int64{mins, maxs}
+}
+
+// hasOnlyUpstreamInstArgs returns whether loong64asm can decode all args of
+// the insn without changes to decodeArg.
+func hasOnlyUpstreamInstArgs(d *common.InsnDescription) bool {
+	for _, a := range manualArgs(d) {
+		if !isUpstreamInstArg(instArgName(d, a)) {
+			return false
+		}
+	}
+	return true
+}
+
+// TestDecodeWithUpstream builds the loong64asm package vendored in GOROOT
+// with the generated tables, and decodes all insns whose args it can
+// handle.
+func TestDecodeWithUpstream(t *testing.T) {
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not found")
+	}
+
+	out, err := exec.Command(goCmd, "env", "GOROOT").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstreamDir := filepath.Join(
+		strings.TrimSpace(string(out)),
+		"src/cmd/vendor/golang.org/x/arch/loong64/loong64asm",
+	)
+	upstreamFiles, err := filepath.Glob(filepath.Join(upstreamDir, "*.go"))
+	if err != nil || len(upstreamFiles) == 0 {
+		t.Skip("loong64asm not vendored in GOROOT")
+	}
+
+	// tests are run in the package directory, one level below the commands
+	descs, err := common.ReadAllInsnDescs(filepath.Join("..", common.TablesDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writeFile := func(name string, content []byte) {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile("go.mod", []byte("module loong64asm\n\ngo 1.21\n"))
+	for _, path := range upstreamFiles {
+		name := filepath.Base(path)
+		if name == "tables.go" || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFile(name, content)
+	}
+	writeFile("tables.go", generate(descs))
+
+	var sb strings.Builder
+	sb.WriteString("package loong64asm\n\n")
+	sb.WriteString("import (\n\t\"encoding/binary\"\n\t\"testing\"\n)\n\n")
+	sb.WriteString("var cases = []struct {\n")
+	sb.WriteString("\tword  uint32\n\top    string\n\tnargs int\n")
+	sb.WriteString("}{\n")
+	n := 0
+	for _, d := range descs {
+		if !hasOnlyUpstreamInstArgs(d) {
+			continue
+		}
+
+		for _, operands := range extremeOperands(d) {
+			word, err := d.Format.Encode(d.Word, operands)
+			if err != nil {
+				t.Fatalf("%s: %v", d.Mnemonic, err)
+			}
+
+			fmt.Fprintf(
+				&sb,
+				"\t{0x%08x, %q, %d},\n",
+				word,
+				strings.ToUpper(d.ManualMnemonic()),
+				len(d.ManualFormat().Args),
+			)
+			n++
+		}
+	}
+	sb.WriteString("}\n")
+	sb.WriteString(decodeTest)
+	writeFile("generated_test.go", []byte(sb.String()))
+
+	if n == 0 {
+		t.Fatal("no insn decodable by loong64asm")
+	}
+
+	cmd := exec.Command(goCmd, "test", "-run", "TestDecodeGenerated", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=mod")
+	out, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go test failed: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+)
+
+var selector = flag.String(
+	"select",
+	"",
+	"only process insns matching this selector, e.g. \"la64,base,lsx\"",
+)
+
+// Takes the insn description files to process as arguments, defaulting to
+// all of them.
+func main() {
+	flag.Parse()
+
+	descs, err := common.ReadSelectedInsnDescs(flag.Args(), *selector)
+	if err != nil {
+		panic(err)
+	}
+
+	os.Stdout.Write(generate(descs))
+}
+
+// generate returns the Python module for the insns.
+func generate(descs []*common.InsnDescription) []byte {
+	sort.Slice(descs, func(i int, j int) bool {
+		return descs[i].Word < descs[j].Word
+	})
+
+	ectx := common.EmitterCtx{
+		DontGofmt: true,
+	}
+
+	ectx.Emit("# SPDX-License-Identifier: MIT\n")
+	ectx.Emit("#\n")
+	ectx.Emit("# LoongArch instruction table, encoder and decoder.\n")
+	ectx.Emit("#\n")
+	ectx.Emit("# This file is auto-generated by genpython from\n")
+	ectx.Emit("# https://github.com/loongson-community/loongarch-opcodes,\n")
+	ectx.Emit("# from commit %s.\n", common.MustGetGitCommitHash())
+	ectx.Emit("# DO NOT EDIT.\n")
+
+	ectx.Emit(pythonPrelude)
+
+	emitArgs(&ectx, descs)
+	emitInsnTable(&ectx, descs)
+
+	ectx.Emit(pythonLookupTable)
+	emitDecoderGroups(&ectx, descs)
+
+	ectx.Emit(pythonFunctions)
+
+	return ectx.Finalize()
+}
+
+////////////////////////////////////////////////////////////////////////////
+
+const pythonPrelude = `
+"""LoongArch instruction table, encoder and decoder.
+
+Operands are given and returned as integers in canonical order, i.e. the
+order of the insn format. Registers are given by index, and immediates by
+their actual values, e.g. branch offsets in bytes.
+"""
+
+from typing import Dict, NamedTuple, Optional, Tuple
+
+
+class Arg(NamedTuple):
+    """An operand of an instruction format."""
+
+    # one of "int_reg", "fp_reg", "fcc_reg", "scratch_reg", "lsx_reg",
+    # "lasx_reg", "signed_imm" and "unsigned_imm"
+    kind: str
+    # (offset, width) of the slots holding the operand, MSB first
+    slots: Tuple[Tuple[int, int], ...]
+    # the value is shifted left by this amount after decoding
+    shift: int = 0
+    # this amount is added to the value after decoding
+    offset: int = 0
+
+    @property
+    def width(self) -> int:
+        return sum(w for _, w in self.slots)
+
+    @property
+    def is_signed(self) -> bool:
+        return self.kind == "signed_imm"
+
+    def value_range(self) -> Tuple[int, int]:
+        """Returns the inclusive range of values of the operand."""
+        if self.is_signed:
+            lo, hi = -(1 << (self.width - 1)), (1 << (self.width - 1)) - 1
+        else:
+            lo, hi = 0, (1 << self.width) - 1
+        return (lo << self.shift) + self.offset, (hi << self.shift) + self.offset
+
+    def validate(self, value: int) -> None:
+        """Raises ValueError if the value is not encodable."""
+        lo, hi = self.value_range()
+        if not lo <= value <= hi:
+            raise ValueError(f"value {value} out of range [{lo}, {hi}]")
+        if (value - self.offset) & ((1 << self.shift) - 1):
+            raise ValueError(f"value {value} not a multiple of {1 << self.shift}")
+
+    def encode(self, value: int) -> int:
+        """Returns the slot bits representing the value."""
+        self.validate(value)
+        x = (value - self.offset) >> self.shift
+        result = 0
+        remaining = self.width
+        for off, w in self.slots:
+            remaining -= w
+            result |= ((x >> remaining) & ((1 << w) - 1)) << off
+        return result
+
+    def extract(self, word: int) -> int:
+        """Returns the value of the operand encoded in the word."""
+        x = 0
+        for off, w in self.slots:
+            x = (x << w) | ((word >> off) & ((1 << w) - 1))
+        if self.is_signed and x & (1 << (self.width - 1)):
+            x -= 1 << self.width
+        return (x << self.shift) + self.offset
+
+
+class Insn(NamedTuple):
+    """An instruction."""
+
+    mnemonic: str
+    # the fixed bits of the instruction words, and their mask
+    word: int
+    mask: int
+    fmt: str
+    args: Tuple[Arg, ...]
+
+`
+
+const pythonLookupTable = `
+
+_BY_MNEMONIC: Dict[str, Insn] = {i.mnemonic: i for i in INSNS}
+`
+
+const pythonFunctions = `
+
+def lookup(mnemonic: str) -> Optional[Insn]:
+    """Returns the instruction with the mnemonic, if any."""
+    return _BY_MNEMONIC.get(mnemonic)
+
+
+def encode(mnemonic: str, *operands: int) -> int:
+    """Encodes the instruction, raising ValueError if it is invalid."""
+    insn = _BY_MNEMONIC.get(mnemonic)
+    if insn is None:
+        raise ValueError(f"unknown instruction {mnemonic}")
+    if len(operands) != len(insn.args):
+        raise ValueError(
+            f"{mnemonic}: expected {len(insn.args)} operands, got {len(operands)}"
+        )
+
+    word = insn.word
+    for i, (arg, value) in enumerate(zip(insn.args, operands)):
+        try:
+            word |= arg.encode(value)
+        except ValueError as e:
+            raise ValueError(f"{mnemonic}: operand {i}: {e}") from None
+    return word
+
+
+def decode(word: int) -> Optional[Tuple[Insn, Tuple[int, ...]]]:
+    """Decodes the instruction word into the instruction and its operands.
+
+    Returns None if no instruction matches.
+    """
+    for mask, insns in _DECODER_GROUPS:
+        insn = insns.get(word & mask)
+        if insn is not None:
+            return insn, tuple(a.extract(word) for a in insn.args)
+    return None
+`
+
+// argKindName returns the name of the arg kind, the same as in the output of
+// genjson.
+func argKindName(k common.ArgKind) string {
+	switch k {
+	case common.ArgKindIntReg:
+		return "int_reg"
+	case common.ArgKindFPReg:
+		return "fp_reg"
+	case common.ArgKindFCCReg:
+		return "fcc_reg"
+	case common.ArgKindScratchReg:
+		return "scratch_reg"
+	case common.ArgKindVReg:
+		return "lsx_reg"
+	case common.ArgKindXReg:
+		return "lasx_reg"
+	case common.ArgKindSignedImm:
+		return "signed_imm"
+	case common.ArgKindUnsignedImm:
+		return "unsigned_imm"
+	default:
+		panic("unreachable")
+	}
+}
+
+// e.g. "_SK16PS2"
+func argConstName(a *common.Arg) string {
+	return "_" + strings.ToUpper(a.CanonicalRepr())
+}
+
+func emitArgs(ectx *common.EmitterCtx, descs []*common.InsnDescription) {
+	argsSet := make(map[string]*common.Arg)
+	for _, d := range descs {
+		for _, a := range d.PostprocessedFormat().Args {
+			argsSet[argConstName(a)] = a
+		}
+	}
+
+	names := make([]string, 0, len(argsSet))
+	for name := range argsSet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ectx.Emit("\n")
+	for _, name := range names {
+		a := argsSet[name]
+
+		slots := make([]string, len(a.Slots))
+		for i, s := range a.Slots {
+			slots[i] = fmt.Sprintf("(%d, %d)", s.Offset, s.Width)
+		}
+		slotsRepr := strings.Join(slots, ", ")
+		if len(slots) == 1 {
+			slotsRepr += ","
+		}
+
+		ectx.Emit("%s = Arg(%q, (%s)", name, argKindName(a.Kind), slotsRepr)
+		switch a.Post.Kind {
+		case common.PostprocessOpKindShl:
+			ectx.Emit(", shift=%d", a.Post.Amount)
+		case common.PostprocessOpKindAdd:
+			ectx.Emit(", offset=%d", a.Post.Amount)
+		}
+		ectx.Emit(")\n")
+	}
+}
+
+func emitInsnTable(ectx *common.EmitterCtx, descs []*common.InsnDescription) {
+	ectx.Emit("\nINSNS: Tuple[Insn, ...] = (\n")
+
+	for _, d := range descs {
+		args := d.PostprocessedFormat().Args
+		names := make([]string, len(args))
+		for i, a := range args {
+			names[i] = argConstName(a)
+		}
+
+		argsRepr := strings.Join(names, ", ")
+		if len(names) == 1 {
+			argsRepr += ","
+		}
+
+		ectx.Emit(
+			"    Insn(%q, 0x%08x, 0x%08x, %q, (%s)),\n",
+			d.Mnemonic,
+			d.Word,
+			d.Format.MatchBitmask(),
+			d.Format.CanonicalRepr(),
+			argsRepr,
+		)
+	}
+
+	ectx.Emit(")\n")
+}
+
+func emitDecoderGroups(ectx *common.EmitterCtx, descs []*common.InsnDescription) {
+	ectx.Emit("\n\n# Instructions grouped by their match masks, most specific masks first, so\n")
+	ectx.Emit("# the first match is the most specific one.\n")
+	ectx.Emit("_DECODER_GROUPS: Tuple[Tuple[int, Dict[int, Insn]], ...] = (\n")
+
+	for _, g := range common.GroupByMatchBitmask(descs) {
+		ectx.Emit("    (\n")
+		ectx.Emit("        0x%08x,\n", g.Mask)
+		ectx.Emit("        {\n")
+		for _, d := range g.Descs {
+			ectx.Emit("            0x%08x: _BY_MNEMONIC[%q],\n", d.Word, d.Mnemonic)
+		}
+		ectx.Emit("        },\n")
+		ectx.Emit("    ),\n")
+	}
+
+	ectx.Emit(")\n")
+}
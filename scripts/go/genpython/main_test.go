@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+)
+
+const roundTripScript = `
+import sys
+
+import insns
+
+failures = 0
+for mnemonic, operands, word in CASES:
+    got = insns.encode(mnemonic, *operands)
+    if got != word:
+        print(f"encoding {mnemonic} {operands}: got {got:08x}, want {word:08x}")
+        failures += 1
+
+    decoded = insns.decode(word)
+    if decoded is None or decoded[0].mnemonic != mnemonic or decoded[1] != operands:
+        print(f"decoding {word:08x}: got {decoded}, want {mnemonic} {operands}")
+        failures += 1
+
+sys.exit(failures != 0)
+`
+
+// extremeOperands returns the operands of the insn all at their minimum, and
+// all at their maximum, with postprocessing applied.
+func extremeOperands(d *common.InsnDescription) [][]int64 {
+	args := d.PostprocessedFormat().Args
+	mins := make([]int64, len(args))
+	maxs := make([]int64, len(args))
+	for i, a := range args {
+		if a.Kind.IsImm() {
+			mins[i], maxs[i] = a.ValueRange()
+		} else {
+			maxs[i] = int64(a.Kind.RegClass().Count() - 1)
+		}
+	}
+	return [][]int64{mins, maxs}
+}
+
+// TestRoundTrip runs a Python script encoding and decoding all insns with
+// the generated module, at the extremes of their operands, and checking the
+// results against common.
+func TestRoundTrip(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not found")
+	}
+
+	// tests are run in the package directory, one level below the commands
+	descs, err := common.ReadAllInsnDescs(filepath.Join("..", common.TablesDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "insns.py"), generate(descs), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("CASES = [\n")
+	for _, d := range descs {
+		for _, operands := range extremeOperands(d) {
+			word, err := d.Encode(operands)
+			if err != nil {
+				t.Fatalf("%s: %v", d.Mnemonic, err)
+			}
+
+			reprs := make([]string, len(operands))
+			for i, val := range operands {
+				reprs[i] = fmt.Sprintf("%d,", val)
+			}
+
+			fmt.Fprintf(&sb, "    (%q, (%s), 0x%08x),\n", d.Mnemonic, strings.Join(reprs, " "), word)
+		}
+	}
+	sb.WriteString("]\n")
+	sb.WriteString(roundTripScript)
+
+	script := filepath.Join(dir, "roundtrip.py")
+	if err := os.WriteFile(script, []byte(sb.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command(python, script).CombinedOutput()
+	if err != nil {
+		t.Fatalf("round trip failed: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+)
+
+var selector = flag.String(
+	"select",
+	"",
+	"only process insns matching this selector, e.g. \"la64,base,lsx\"",
+)
+
+// Takes the insn description files to process as arguments, defaulting to
+// all of them.
+func main() {
+	flag.Parse()
+
+	descs, err := common.ReadSelectedInsnDescs(flag.Args(), *selector)
+	if err != nil {
+		panic(err)
+	}
+
+	sort.Slice(descs, func(i int, j int) bool {
+		return descs[i].Word < descs[j].Word
+	})
+
+	formats := gatherFormats(descs)
+
+	var ectx common.EmitterCtx
+	ectx.DontGofmt = true
+
+	ectx.Emit("# SPDX-License-Identifier: MIT\n")
+	ectx.Emit("#\n")
+	ectx.Emit("# LoongArch instruction decode definitions.\n")
+	ectx.Emit("#\n")
+	ectx.Emit("# This file is auto-generated by genqemudecodetree from\n")
+	ectx.Emit("# https://github.com/loongson-community/loongarch-opcodes,\n")
+	ectx.Emit("# from commit %s.\n", common.MustGetGitCommitHash())
+	ectx.Emit("# DO NOT EDIT.\n")
+	ectx.Emit("#\n")
+
+	emitFields(&ectx, formats)
+	emitArgsets(&ectx, formats)
+	emitFormats(&ectx, formats)
+	emitPatterns(&ectx, descs)
+
+	result := ectx.Finalize()
+	os.Stdout.Write(result)
+}
+
+////////////////////////////////////////////////////////////////////////////
+
+// gatherFormats returns the distinct postprocessed formats of the insns,
+// sorted by name.
+//
+// Insns sharing the same canonical format may differ in postprocess ops,
+// e.g. "beq" has its offset shifted while "addu16i.d" has not, so they need
+// different decodetree formats.
+func gatherFormats(descs []*common.InsnDescription) []*common.InsnFormat {
+	formatsSet := make(map[string]*common.InsnFormat)
+	for _, d := range descs {
+		f := d.PostprocessedFormat()
+		name := formatName(f)
+		if _, ok := formatsSet[name]; !ok {
+			formatsSet[name] = f
+		}
+	}
+
+	result := make([]*common.InsnFormat, 0, len(formatsSet))
+	for _, f := range formatsSet {
+		result = append(result, f)
+	}
+
+	sort.Slice(result, func(i int, j int) bool {
+		return formatName(result[i]) < formatName(result[j])
+	})
+
+	return result
+}
+
+// formatName returns the name of the decodetree format for the
+// postprocessed format, e.g. "djsk16ps2".
+func formatName(f *common.InsnFormat) string {
+	if len(f.Args) == 0 {
+		return "empty"
+	}
+	return strings.ToLower(f.CanonicalRepr())
+}
+
+// argsetName returns the name of the decodetree argument set for the
+// format, which ignores postprocess ops, e.g. "djsk16".
+func argsetName(f *common.InsnFormat) string {
+	if len(f.Args) == 0 {
+		return "empty"
+	}
+
+	var sb strings.Builder
+	for _, a := range f.Args {
+		sb.WriteString(argName(a))
+	}
+	return sb.String()
+}
+
+// argName returns the name of the arg in argument sets, e.g. "sk16".
+func argName(a *common.Arg) string {
+	plain := common.Arg{
+		Kind:  a.Kind,
+		Slots: a.Slots,
+	}
+	return strings.ToLower(plain.CanonicalRepr())
+}
+
+// fieldName returns the name of the %field definition of the arg, e.g.
+// "sk16ps2".
+func fieldName(a *common.Arg) string {
+	return strings.ToLower(a.CanonicalRepr())
+}
+
+// needsFieldDef reports whether the arg cannot be expressed as an inline
+// field of a format.
+func needsFieldDef(a *common.Arg) bool {
+	return a.Kind == common.ArgKindSignedImm ||
+		len(a.Slots) > 1 ||
+		a.Post.Kind != common.PostprocessOpKindNone
+}
+
+func emitFields(ectx *common.EmitterCtx, formats []*common.InsnFormat) {
+	fieldsSet := make(map[string]*common.Arg)
+	for _, f := range formats {
+		for _, a := range f.Args {
+			if needsFieldDef(a) {
+				fieldsSet[fieldName(a)] = a
+			}
+		}
+	}
+
+	names := make([]string, 0, len(fieldsSet))
+	for name := range fieldsSet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ectx.Emit("\n#\n# Fields\n#\n")
+
+	for _, name := range names {
+		a := fieldsSet[name]
+
+		var segments []string
+		for i, s := range a.Slots {
+			sign := ""
+			if i == 0 && a.Kind == common.ArgKindSignedImm {
+				// the first slot holds the MSB
+				sign = "s"
+			}
+			segments = append(segments, fmt.Sprintf("%d:%s%d", s.Offset, sign, s.Width))
+		}
+
+		ectx.Emit("%%%-16s %s", name, strings.Join(segments, " "))
+
+		switch a.Post.Kind {
+		case common.PostprocessOpKindAdd:
+			ectx.Emit(" !function=plus_%d", a.Post.Amount)
+		case common.PostprocessOpKindShl:
+			ectx.Emit(" !function=shl_%d", a.Post.Amount)
+		}
+
+		ectx.Emit("\n")
+	}
+}
+
+func emitArgsets(ectx *common.EmitterCtx, formats []*common.InsnFormat) {
+	argsets := make(map[string][]string)
+	for _, f := range formats {
+		var args []string
+		for _, a := range f.Args {
+			args = append(args, argName(a))
+		}
+		argsets[argsetName(f)] = args
+	}
+
+	names := make([]string, 0, len(argsets))
+	for name := range argsets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ectx.Emit("\n#\n# Argument sets\n#\n")
+
+	for _, name := range names {
+		if len(argsets[name]) == 0 {
+			ectx.Emit("&%s\n", name)
+			continue
+		}
+		ectx.Emit("&%-16s %s\n", name, strings.Join(argsets[name], " "))
+	}
+}
+
+func emitFormats(ectx *common.EmitterCtx, formats []*common.InsnFormat) {
+	ectx.Emit("\n#\n# Formats\n#\n")
+
+	for _, f := range formats {
+		// inline fields, keyed by the MSB of their only slot
+		inlineFields := make(map[int]*common.Arg)
+		var fieldRefs []string
+		for _, a := range f.Args {
+			if needsFieldDef(a) {
+				fieldRefs = append(fieldRefs, fmt.Sprintf("%s=%%%s", argName(a), fieldName(a)))
+				continue
+			}
+			inlineFields[int(a.Slots[0].MSB())] = a
+		}
+
+		var tokens []string
+		var dots strings.Builder
+		for bit := 31; bit >= 0; {
+			if a, ok := inlineFields[bit]; ok {
+				if dots.Len() > 0 {
+					tokens = append(tokens, dots.String())
+					dots.Reset()
+				}
+				tokens = append(tokens, fmt.Sprintf("%s:%d", argName(a), a.Slots[0].Width))
+				bit -= int(a.Slots[0].Width)
+				continue
+			}
+
+			dots.WriteRune('.')
+			bit--
+		}
+		if dots.Len() > 0 {
+			tokens = append(tokens, dots.String())
+		}
+
+		ectx.Emit("@%-16s %s &%s", formatName(f), strings.Join(tokens, " "), argsetName(f))
+		for _, ref := range fieldRefs {
+			ectx.Emit(" %s", ref)
+		}
+		ectx.Emit("\n")
+	}
+}
+
+// e.g. "amswap_db.w" -> "amswap_db_w"
+func patternName(mnemonic string) string {
+	return strings.ReplaceAll(mnemonic, ".", "_")
+}
+
+func emitPatterns(ectx *common.EmitterCtx, descs []*common.InsnDescription) {
+	ectx.Emit("\n#\n# Patterns\n#\n")
+
+	seen := make(map[string]string)
+	for _, d := range descs {
+		name := patternName(d.Mnemonic)
+		if prev, ok := seen[name]; ok {
+			panic(fmt.Sprintf("pattern name %s shared by %s and %s", name, prev, d.Mnemonic))
+		}
+		seen[name] = d.Mnemonic
+
+		mask := d.Format.MatchBitmask()
+
+		var sb strings.Builder
+		for bit := 31; bit >= 0; bit-- {
+			switch {
+			case mask&(1<<bit) == 0:
+				sb.WriteRune('.')
+			case d.Word&(1<<bit) != 0:
+				sb.WriteRune('1')
+			default:
+				sb.WriteRune('0')
+			}
+
+			if bit > 0 && bit%4 == 0 {
+				sb.WriteRune(' ')
+			}
+		}
+
+		ectx.Emit("%-20s %s @%s\n", name, sb.String(), formatName(d.PostprocessedFormat()))
+	}
+}
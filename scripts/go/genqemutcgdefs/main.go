@@ -24,12 +24,7 @@ func main() {
 	// unconditionally take all instruction description files,
 	// filtering is done by individually attaching @qemu attribute for
 	// insns we want to use
-	inputs, err := filepath.Glob("../../*.txt")
-	if err != nil {
-		panic(err)
-	}
-
-	descs, err := common.ReadInsnDescs(inputs)
+	descs, err := common.ReadAllInsnDescs(common.TablesDir)
 	if err != nil {
 		panic(err)
 	}
@@ -128,7 +123,7 @@ func main() {
 func filterUnusedInsns(descs []*common.InsnDescription) []*common.InsnDescription {
 	var result []*common.InsnDescription
 	for _, d := range descs {
-		if _, ok := d.Attribs["qemu"]; !ok {
+		if !d.Attribs.QEMU {
 			// QEMU TCG doesn't emit this instruction for now, so ignore this
 			// to reduce code size.
 			continue
@@ -419,36 +414,17 @@ func emitFmtEncoderFn(ectx *common.EmitterCtx, f *common.InsnFormat) {
 				}
 			}
 		} else {
-			// remainingBits is shift amount to extract the current slot from arg
-			//
-			// take example of Sd5k16:
-			//
-			// Sd5k16 = (MSB) DDDDDKKKKKKKKKKKKKKKK (LSB)
-			//
-			// initially remainingBits = 5+16
-			//
-			// consume from left to right:
-			//
-			// slot d5: remainingBits = 16
-			// thus d5 = (sd5k16 >> 16) & 0b11111
-			// emit (d5 expr above)
-			//
-			// slot k16: remainingBits = 0
-			// thus k16 = (sd5k16 >> 0) & 0b1111111111111111
-			//          = sd5k16 & 0b1111111111111111
-			// emit (k16 expr above)
-			remainingBits := int(a.TotalWidth())
-			for _, s := range a.Slots {
-				remainingBits -= int(s.Width)
-				mask := int((1 << s.Width) - 1)
+			// see (*common.Arg).SlotParts for how the arg is split into slots
+			for _, p := range a.SlotParts() {
+				mask := int((1 << p.Slot.Width) - 1)
 
 				var sb strings.Builder
 
-				if remainingBits > 0 {
+				if p.Shift > 0 {
 					sb.WriteRune('(')
 					sb.WriteString(argVarName)
 					sb.WriteString(" >> ")
-					sb.WriteString(strconv.Itoa(remainingBits))
+					sb.WriteString(strconv.Itoa(int(p.Shift)))
 					sb.WriteRune(')')
 				} else {
 					sb.WriteString(argVarName)
@@ -457,7 +433,7 @@ func emitFmtEncoderFn(ectx *common.EmitterCtx, f *common.InsnFormat) {
 				sb.WriteString(" & 0x")
 				sb.WriteString(strconv.FormatUint(uint64(mask), 16))
 
-				slotExprs[s.Offset] = sb.String()
+				slotExprs[p.Slot.Offset] = sb.String()
 			}
 		}
 	}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+)
+
+var selector = flag.String(
+	"select",
+	"",
+	"only process insns matching this selector, e.g. \"la64,base,lsx\"",
+)
+
+// Takes the insn description files to process as arguments, defaulting to
+// all of them.
+func main() {
+	flag.Parse()
+
+	descs, err := common.ReadSelectedInsnDescs(flag.Args(), *selector)
+	if err != nil {
+		panic(err)
+	}
+
+	result := generate(descs)
+
+	// format the generated code with rustfmt
+	rustfmt := exec.Command("rustfmt", "--edition", "2021")
+	rustfmt.Stdin = bytes.NewBuffer(result)
+	formattedResult, err := rustfmt.Output()
+	if err != nil {
+		exitError, ok := err.(*exec.ExitError)
+		if !ok {
+			panic(err)
+		}
+		fmt.Fprintf(os.Stderr, "fatal: rustfmt failed\nstderr:\n%s", string(exitError.Stderr))
+		panic(err)
+	}
+
+	os.Stdout.Write(formattedResult)
+}
+
+// generate returns the Rust module for the insns, before formatting.
+func generate(descs []*common.InsnDescription) []byte {
+	formats := common.GatherFormats(descs)
+	scs := common.GatherSlotCombinations(formats)
+
+	sort.Slice(descs, func(i int, j int) bool {
+		return descs[i].Word < descs[j].Word
+	})
+
+	ectx := common.EmitterCtx{
+		DontGofmt: true,
+	}
+
+	ectx.Emit("// SPDX-License-Identifier: MIT\n")
+	ectx.Emit("//\n")
+	ectx.Emit("// LoongArch instruction opcodes, formats, encoders and decoder.\n")
+	ectx.Emit("//\n")
+	ectx.Emit("// This file is auto-generated by genrust from\n")
+	ectx.Emit("// https://github.com/loongson-community/loongarch-opcodes,\n")
+	ectx.Emit("// from commit %s.\n", common.MustGetGitCommitHash())
+	ectx.Emit("// DO NOT EDIT.\n")
+	ectx.Emit("\n#![allow(dead_code)]\n")
+
+	for _, rt := range regTypes {
+		emitRegType(&ectx, rt)
+	}
+
+	emitFormatEnum(&ectx, formats)
+	emitOpcodeEnum(&ectx, descs)
+
+	for _, sc := range scs {
+		emitSlotEncoderFn(&ectx, sc)
+	}
+
+	for _, f := range formats {
+		emitFmtEncoderFn(&ectx, f)
+	}
+
+	emitDecoder(&ectx, descs)
+
+	ectx.Emit("\n// End of generated code.\n")
+
+	return ectx.Finalize()
+}
+
+////////////////////////////////////////////////////////////////////////////
+
+// regType is a Rust newtype for registers referred to by args of some kind.
+type regType struct {
+	kind common.ArgKind
+	name string
+	doc  string
+}
+
+var regTypes = []regType{
+	{kind: common.ArgKindIntReg, name: "Gpr", doc: "An integer register."},
+	{kind: common.ArgKindFPReg, name: "Fpr", doc: "An FP register."},
+	{kind: common.ArgKindFCCReg, name: "Fcc", doc: "An FCC register."},
+	{kind: common.ArgKindScratchReg, name: "Scr", doc: "A scratch register."},
+	{kind: common.ArgKindVReg, name: "Vr", doc: "An LSX register."},
+	{kind: common.ArgKindXReg, name: "Xr", doc: "A LASX register."},
+}
+
+func regTypeNameForArgKind(k common.ArgKind) string {
+	for _, rt := range regTypes {
+		if rt.kind == k {
+			return rt.name
+		}
+	}
+	panic("should never happen")
+}
+
+func emitRegType(ectx *common.EmitterCtx, rt regType) {
+	class := rt.kind.RegClass()
+
+	ectx.Emit("\n/// %s\n", rt.doc)
+	ectx.Emit("#[derive(Clone, Copy, Debug, PartialEq, Eq, Hash)]\n")
+	ectx.Emit("pub struct %s(u8);\n", rt.name)
+	ectx.Emit("\nimpl %s {\n", rt.name)
+	ectx.Emit("    /// Returns the register with the given index, or `None` if out of range.\n")
+	ectx.Emit("    pub const fn new(index: u8) -> Option<Self> {\n")
+	ectx.Emit("        if index < %d {\n", class.Count())
+	ectx.Emit("            Some(Self(index))\n")
+	ectx.Emit("        } else {\n")
+	ectx.Emit("            None\n")
+	ectx.Emit("        }\n")
+	ectx.Emit("    }\n")
+	ectx.Emit("\n")
+	ectx.Emit("    /// Returns the index of the register.\n")
+	ectx.Emit("    pub const fn index(self) -> u8 {\n")
+	ectx.Emit("        self.0\n")
+	ectx.Emit("    }\n")
+	ectx.Emit("}\n")
+}
+
+////////////////////////////////////////////////////////////////////////////
+
+// e.g. "DJSk12" -> "DJSk12", "EMPTY" -> "Empty"
+func formatVariantName(f *common.InsnFormat) string {
+	if len(f.Args) == 0 {
+		return "Empty"
+	}
+	return f.CanonicalRepr()
+}
+
+func emitFormatEnum(ectx *common.EmitterCtx, formats []*common.InsnFormat) {
+	ectx.Emit("\n/// Instruction formats, named after their canonical representation.\n")
+	ectx.Emit("#[derive(Clone, Copy, Debug, PartialEq, Eq, Hash)]\n")
+	ectx.Emit("pub enum Format {\n")
+
+	for _, f := range formats {
+		ectx.Emit("    %s,\n", formatVariantName(f))
+	}
+
+	ectx.Emit("}\n")
+}
+
+// e.g. "amadd_db.w" -> "AmaddDbW"
+func insnMnemonicToEnumVariantName(x string) string {
+	var sb strings.Builder
+	for _, part := range strings.FieldsFunc(x, func(r rune) bool {
+		return r == '.' || r == '_'
+	}) {
+		sb.WriteString(strings.ToUpper(part[:1]))
+		sb.WriteString(part[1:])
+	}
+	return sb.String()
+}
+
+// transform InsnDescription to syntax example, e.g. "addi.d d, j, sk12"
+func insnSyntaxDescForInsn(d *common.InsnDescription) string {
+	if len(d.Format.Args) == 0 {
+		// special-case EMPTY
+		return d.Mnemonic
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(d.Mnemonic)
+	for i, a := range d.Format.Args {
+		if i == 0 {
+			sb.WriteRune(' ')
+		} else {
+			sb.WriteString(", ")
+		}
+
+		sb.WriteString(strings.ToLower(a.CanonicalRepr()))
+	}
+
+	return sb.String()
+}
+
+func emitOpcodeEnum(ectx *common.EmitterCtx, descs []*common.InsnDescription) {
+	variantNames := make([]string, len(descs))
+	seen := make(map[string]string)
+	for i, d := range descs {
+		name := insnMnemonicToEnumVariantName(d.Mnemonic)
+		if prev, ok := seen[name]; ok {
+			panic(fmt.Sprintf("variant name %s shared by %s and %s", name, prev, d.Mnemonic))
+		}
+		seen[name] = d.Mnemonic
+		variantNames[i] = name
+	}
+
+	ectx.Emit("\n/// Instruction opcodes, valued the fixed bits of their instruction words.\n")
+	ectx.Emit("#[derive(Clone, Copy, Debug, PartialEq, Eq, Hash)]\n")
+	ectx.Emit("#[repr(u32)]\n")
+	ectx.Emit("pub enum Opcode {\n")
+	for i, d := range descs {
+		ectx.Emit("    /// `%s`\n", insnSyntaxDescForInsn(d))
+		ectx.Emit("    %s = 0x%08x,\n", variantNames[i], d.Word)
+	}
+	ectx.Emit("}\n")
+
+	ectx.Emit("\nimpl Opcode {\n")
+
+	ectx.Emit("    /// Returns the mnemonic of the instruction.\n")
+	ectx.Emit("    pub const fn mnemonic(self) -> &'static str {\n")
+	ectx.Emit("        match self {\n")
+	for i, d := range descs {
+		ectx.Emit("            Self::%s => \"%s\",\n", variantNames[i], d.Mnemonic)
+	}
+	ectx.Emit("        }\n")
+	ectx.Emit("    }\n")
+
+	ectx.Emit("\n")
+	ectx.Emit("    /// Returns the format of the instruction.\n")
+	ectx.Emit("    pub const fn format(self) -> Format {\n")
+	ectx.Emit("        match self {\n")
+	for i, d := range descs {
+		ectx.Emit("            Self::%s => Format::%s,\n", variantNames[i], formatVariantName(d.Format))
+	}
+	ectx.Emit("        }\n")
+	ectx.Emit("    }\n")
+
+	ectx.Emit("\n")
+	ectx.Emit("    /// Returns the mask of the fixed bits of the instruction words.\n")
+	ectx.Emit("    pub const fn match_mask(self) -> u32 {\n")
+	ectx.Emit("        match self {\n")
+	for i, d := range descs {
+		ectx.Emit("            Self::%s => 0x%08x,\n", variantNames[i], d.Format.MatchBitmask())
+	}
+	ectx.Emit("        }\n")
+	ectx.Emit("    }\n")
+
+	ectx.Emit("}\n")
+}
+
+////////////////////////////////////////////////////////////////////////////
+
+func slotEncoderFnNameForSc(sc string) string {
+	plural := ""
+	if len(sc) > 1 {
+		plural = "s"
+	}
+
+	return fmt.Sprintf("encode_%s_slot%s", strings.ToLower(sc), plural)
+}
+
+func emitSlotEncoderFn(ectx *common.EmitterCtx, sc string) {
+	funcName := slotEncoderFnNameForSc(sc)
+	scLower := strings.ToLower(sc)
+
+	ectx.Emit("\nconst fn %s(opc: u32", funcName)
+	for _, s := range scLower {
+		ectx.Emit(", %c: u32", s)
+	}
+	ectx.Emit(") -> u32 {\n")
+
+	ectx.Emit("    opc")
+
+	for _, s := range scLower {
+		offset := common.SlotOffsetForChar(s)
+
+		ectx.Emit(" | %c", s)
+		if offset > 0 {
+			ectx.Emit(" << %d", offset)
+		}
+	}
+
+	ectx.Emit("\n}\n")
+}
+
+func fmtEncoderFnNameForInsnFormat(f *common.InsnFormat) string {
+	return fmt.Sprintf("encode_%s_insn", strings.ToLower(f.CanonicalRepr()))
+}
+
+func argVarName(a *common.Arg) string {
+	return strings.ToLower(a.CanonicalRepr())
+}
+
+func argType(a *common.Arg) string {
+	switch a.Kind {
+	case common.ArgKindSignedImm:
+		return "i32"
+	case common.ArgKindUnsignedImm:
+		return "u32"
+	default:
+		return regTypeNameForArgKind(a.Kind)
+	}
+}
+
+func emitFmtEncoderFn(ectx *common.EmitterCtx, f *common.InsnFormat) {
+	ectx.Emit("\n/// Encodes an instruction of the `%s` format.\n", formatVariantName(f))
+	ectx.Emit("///\n")
+	ectx.Emit("/// Immediates are taken as encoded, without any scaling or offsetting\n")
+	ectx.Emit("/// applied. Returns `None` if the opcode is not of the format, or any\n")
+	ectx.Emit("/// immediate is out of range.\n")
+
+	ectx.Emit("pub fn %s(opc: Opcode", fmtEncoderFnNameForInsnFormat(f))
+	for _, a := range f.Args {
+		ectx.Emit(", %s: %s", argVarName(a), argType(a))
+	}
+	ectx.Emit(") -> Option<u32> {\n")
+
+	ectx.Emit("    if opc.format() != Format::%s {\n", formatVariantName(f))
+	ectx.Emit("        return None;\n")
+	ectx.Emit("    }\n")
+
+	// EMPTY has nothing more to encode after all
+	if len(f.Args) == 0 {
+		ectx.Emit("    Some(opc as u32)\n")
+		ectx.Emit("}\n")
+		return
+	}
+
+	for _, a := range f.Args {
+		varName := argVarName(a)
+
+		switch a.Kind {
+		case common.ArgKindSignedImm:
+			// -min <= x <= max
+			max := (1 << (a.TotalWidth() - 1)) - 1
+			negativeMin := max + 1
+			ectx.Emit("    if !(-0x%x..=0x%x).contains(&%s) {\n", negativeMin, max, varName)
+			ectx.Emit("        return None;\n")
+			ectx.Emit("    }\n")
+
+		case common.ArgKindUnsignedImm:
+			// x <= max
+			max := (1 << a.TotalWidth()) - 1
+			ectx.Emit("    if %s > 0x%x {\n", varName, max)
+			ectx.Emit("        return None;\n")
+			ectx.Emit("    }\n")
+		}
+	}
+
+	// collect slot expressions
+	slotExprs := make(map[uint]string)
+	for _, a := range f.Args {
+		varName := argVarName(a)
+
+		if len(a.Slots) == 1 {
+			switch a.Kind {
+			case common.ArgKindSignedImm:
+				// signed imms need masking to convert to unsigned slot value
+				mask := (1 << a.TotalWidth()) - 1
+				slotExprs[a.Slots[0].Offset] = fmt.Sprintf("(%s & 0x%x) as u32", varName, mask)
+			case common.ArgKindUnsignedImm:
+				slotExprs[a.Slots[0].Offset] = varName
+			default:
+				slotExprs[a.Slots[0].Offset] = fmt.Sprintf("%s.0 as u32", varName)
+			}
+			continue
+		}
+
+		// see (*common.Arg).SlotParts for how the arg is split into slots
+		for _, p := range a.SlotParts() {
+			mask := (1 << p.Slot.Width) - 1
+
+			expr := varName
+			if p.Shift > 0 {
+				expr = fmt.Sprintf("(%s >> %d)", varName, p.Shift)
+			}
+			expr = fmt.Sprintf("%s & 0x%x", expr, mask)
+
+			if a.Kind == common.ArgKindSignedImm {
+				expr = fmt.Sprintf("(%s) as u32", expr)
+			}
+
+			slotExprs[p.Slot.Offset] = expr
+		}
+	}
+
+	sc := f.SlotCombination()
+	encFnName := slotEncoderFnNameForSc(sc)
+
+	slotArgs := []string{"opc as u32"}
+	for _, s := range sc {
+		offset := common.SlotOffsetForChar(s)
+		slotExpr, ok := slotExprs[offset]
+		if !ok {
+			panic("should never happen")
+		}
+		slotArgs = append(slotArgs, slotExpr)
+	}
+
+	ectx.Emit("    Some(%s(%s))\n", encFnName, strings.Join(slotArgs, ", "))
+	ectx.Emit("}\n")
+}
+
+////////////////////////////////////////////////////////////////////////////
+
+func emitDecoder(ectx *common.EmitterCtx, descs []*common.InsnDescription) {
+	// descs are already sorted by word, and so are the insns of each group
+	groups := common.GroupByMatchBitmask(descs)
+
+	ectx.Emit("\n/// Opcodes grouped by their match masks, most specific masks first, and\n")
+	ectx.Emit("/// sorted by their words within each group.\n")
+	ectx.Emit("const DECODER_GROUPS: &[(u32, &[(u32, Opcode)])] = &[\n")
+	for _, g := range groups {
+		ectx.Emit("    (\n")
+		ectx.Emit("        0x%08x,\n", g.Mask)
+		ectx.Emit("        &[\n")
+		for _, d := range g.Descs {
+			ectx.Emit(
+				"            (0x%08x, Opcode::%s),\n",
+				d.Word,
+				insnMnemonicToEnumVariantName(d.Mnemonic),
+			)
+		}
+		ectx.Emit("        ],\n")
+		ectx.Emit("    ),\n")
+	}
+	ectx.Emit("];\n")
+
+	ectx.Emit("\n/// Returns the opcode of the instruction word, or `None` if no instruction\n")
+	ectx.Emit("/// matches.\n")
+	ectx.Emit("pub fn decode(word: u32) -> Option<Opcode> {\n")
+	ectx.Emit("    for &(mask, opcodes) in DECODER_GROUPS {\n")
+	ectx.Emit("        if let Ok(i) = opcodes.binary_search_by_key(&(word & mask), |&(w, _)| w) {\n")
+	ectx.Emit("            return Some(opcodes[i].1);\n")
+	ectx.Emit("        }\n")
+	ectx.Emit("    }\n")
+	ectx.Emit("    None\n")
+	ectx.Emit("}\n")
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+)
+
+// extremeOperands returns the operands of the insn all at their minimum, and
+// all at their maximum, without postprocessing.
+func extremeOperands(d *common.InsnDescription) [][]int64 {
+	mins := make([]int64, len(d.Format.Args))
+	maxs := make([]int64, len(d.Format.Args))
+	for i, a := range d.Format.Args {
+		if !a.Kind.IsImm() {
+			maxs[i] = int64(a.Kind.RegClass().Count() - 1)
+			continue
+		}
+
+		width := a.TotalWidth()
+		if a.Kind == common.ArgKindSignedImm {
+			mins[i] = -(1 << (width - 1))
+			maxs[i] = 1<<(width-1) - 1
+		} else {
+			maxs[i] = 1<<width - 1
+		}
+	}
+	return [][]int64{mins, maxs}
+}
+
+func rustOperand(a *common.Arg, val int64) string {
+	if a.Kind.IsImm() {
+		return fmt.Sprintf("%d", val)
+	}
+	return fmt.Sprintf("%s::new(%d).unwrap()", regTypeNameForArgKind(a.Kind), val)
+}
+
+// TestRoundTrip compiles and runs a program checking the generated encoders
+// and decoder against common for all insns, at the extremes of their
+// operands.
+func TestRoundTrip(t *testing.T) {
+	rustc, err := exec.LookPath("rustc")
+	if err != nil {
+		t.Skip("rustc not found")
+	}
+
+	// tests are run in the package directory, one level below the commands
+	descs, err := common.ReadAllInsnDescs(filepath.Join("..", common.TablesDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	sb.Write(generate(descs))
+
+	sb.WriteString("\nfn main() {\n")
+	for _, d := range descs {
+		variant := insnMnemonicToEnumVariantName(d.Mnemonic)
+
+		for _, operands := range extremeOperands(d) {
+			word, err := d.Format.Encode(d.Word, operands)
+			if err != nil {
+				t.Fatalf("%s: %v", d.Mnemonic, err)
+			}
+
+			args := []string{"Opcode::" + variant}
+			for i, a := range d.Format.Args {
+				args = append(args, rustOperand(a, operands[i]))
+			}
+
+			fmt.Fprintf(
+				&sb,
+				"    assert_eq!(%s(%s), Some(0x%08x), \"encoding %s %v\");\n",
+				fmtEncoderFnNameForInsnFormat(d.Format),
+				strings.Join(args, ", "),
+				word,
+				d.Mnemonic,
+				operands,
+			)
+			fmt.Fprintf(
+				&sb,
+				"    assert_eq!(decode(0x%08x), Some(Opcode::%s), \"decoding %s\");\n",
+				word,
+				variant,
+				d.Mnemonic,
+			)
+		}
+	}
+	sb.WriteString("}\n")
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "roundtrip.rs")
+	bin := filepath.Join(dir, "roundtrip")
+	if err := os.WriteFile(src, []byte(sb.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command(rustc, "--edition", "2021", "-o", bin, src).CombinedOutput()
+	if err != nil {
+		t.Fatalf("rustc failed: %v\n%s", err, out)
+	}
+
+	out, err = exec.Command(bin).CombinedOutput()
+	if err != nil {
+		t.Fatalf("round trip failed: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+)
+
+var selector = flag.String(
+	"select",
+	"",
+	"only process insns matching this selector, e.g. \"la64,base,lsx\"",
+)
+
+var manual = flag.Bool(
+	"manual",
+	false,
+	"use the manual syntax, i.e. upstream mnemonics and operand order",
+)
+
+// Takes the insn description files to process as arguments, defaulting to
+// all of them.
+//
+// Register roles are not described by the tables, so all operands are
+// listed as inputs, and FP registers are of the generic "FPR" class; adjust
+// as necessary when pasting into LLVM.
+func main() {
+	flag.Parse()
+
+	descs, err := common.ReadSelectedInsnDescs(flag.Args(), *selector)
+	if err != nil {
+		panic(err)
+	}
+
+	// group insns by predicates, then sort by encoding within groups
+	sort.SliceStable(descs, func(i int, j int) bool {
+		pi := strings.Join(predicatesForInsn(descs[i]), ", ")
+		pj := strings.Join(predicatesForInsn(descs[j]), ", ")
+		if pi != pj {
+			return pi < pj
+		}
+		return descs[i].Word < descs[j].Word
+	})
+
+	var ectx common.EmitterCtx
+	ectx.DontGofmt = true
+
+	ectx.Emit("// SPDX-License-Identifier: MIT\n")
+	ectx.Emit("//\n")
+	ectx.Emit("// LoongArch instruction formats and definitions.\n")
+	ectx.Emit("//\n")
+	ectx.Emit("// This file is auto-generated by gentablegen from\n")
+	ectx.Emit("// https://github.com/loongson-community/loongarch-opcodes,\n")
+	ectx.Emit("// from commit %s.\n", common.MustGetGitCommitHash())
+	ectx.Emit("// DO NOT EDIT.\n")
+
+	emitFormatClasses(&ectx, descs)
+	emitInsnDefs(&ectx, descs)
+
+	result := ectx.Finalize()
+	os.Stdout.Write(result)
+}
+
+////////////////////////////////////////////////////////////////////////////
+
+// formatClassName returns the name of the TableGen class of the insn
+// format, e.g. "FmtDJSk12".
+func formatClassName(f *common.InsnFormat) string {
+	return "Fmt" + f.CanonicalRepr()
+}
+
+// fcsrMask returns the bitmask of the args of the insn that are FCSR
+// operands, i.e. immediates in the canonical syntax but integer registers in
+// the manual one.
+func fcsrMask(d *common.InsnDescription) uint32 {
+	var result uint32
+	for _, ma := range d.ManualFormat().Args {
+		if ma.Kind != common.ArgKindIntReg {
+			continue
+		}
+		for _, ca := range d.Format.Args {
+			if ca.Bitmask() == ma.Bitmask() && ca.Kind.IsImm() {
+				result |= ca.Bitmask()
+			}
+		}
+	}
+	return result
+}
+
+// fieldName returns the name of the operand field of the arg in the
+// format, e.g. "rd" or "imm12", following the LLVM backend's convention.
+//
+// FCSR operands, given by fcsrMask, are named "fcsr". Other immediates are
+// named after their total width, unless another immediate of the format has
+// the same width, in which case they are named after their slots, e.g.
+// "um5" and "uk5".
+func fieldName(f *common.InsnFormat, fcsrMask uint32, a *common.Arg) string {
+	if a.Bitmask()&fcsrMask != 0 {
+		return "fcsr"
+	}
+
+	switch a.Kind {
+	case common.ArgKindIntReg:
+		return "r" + strings.ToLower(a.CanonicalRepr())
+
+	case common.ArgKindSignedImm, common.ArgKindUnsignedImm:
+		for _, other := range f.Args {
+			if other == a || other.Bitmask()&fcsrMask != 0 {
+				continue
+			}
+			if other.Kind.IsImm() && other.TotalWidth() == a.TotalWidth() {
+				return strings.ToLower(a.CanonicalRepr())
+			}
+		}
+		return fmt.Sprintf("imm%d", a.TotalWidth())
+
+	default:
+		return strings.ToLower(a.CanonicalRepr())
+	}
+}
+
+// operandType returns the TableGen operand type of the postprocessed arg,
+// e.g. "GPR" or "simm16_lsl2".
+func operandType(a *common.Arg) string {
+	switch a.Kind {
+	case common.ArgKindIntReg:
+		return "GPR"
+	case common.ArgKindFPReg:
+		return "FPR"
+	case common.ArgKindFCCReg:
+		return "CFR"
+	case common.ArgKindScratchReg:
+		return "SCR"
+	case common.ArgKindVReg:
+		return "LSX128"
+	case common.ArgKindXReg:
+		return "LASX256"
+	}
+
+	prefix := "uimm"
+	if a.Kind == common.ArgKindSignedImm {
+		prefix = "simm"
+	}
+
+	result := fmt.Sprintf("%s%d", prefix, a.TotalWidth())
+	switch a.Post.Kind {
+	case common.PostprocessOpKindAdd:
+		result += fmt.Sprintf("_plus%d", a.Post.Amount)
+	case common.PostprocessOpKindShl:
+		result += fmt.Sprintf("_lsl%d", a.Post.Amount)
+	}
+
+	return result
+}
+
+func emitFormatClasses(ectx *common.EmitterCtx, descs []*common.InsnDescription) {
+	// field names are per format, so all insns of a format must agree on
+	// which args are FCSR operands
+	formatsSet := make(map[string]*common.InsnFormat)
+	fcsrMasks := make(map[string]uint32)
+	for _, d := range descs {
+		name := formatClassName(d.Format)
+		mask := fcsrMask(d)
+		if prev, ok := fcsrMasks[name]; ok && prev != mask {
+			panic(fmt.Sprintf("insns of format %s disagree on FCSR operands", d.Format.CanonicalRepr()))
+		}
+
+		formatsSet[name] = d.Format
+		fcsrMasks[name] = mask
+	}
+
+	names := make([]string, 0, len(formatsSet))
+	for name := range formatsSet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ectx.Emit("\n//===----------------------------------------------------------------------===//\n")
+	ectx.Emit("// Instruction formats\n")
+	ectx.Emit("//===----------------------------------------------------------------------===//\n")
+
+	for _, name := range names {
+		f := formatsSet[name]
+		mask := fcsrMasks[name]
+
+		ectx.Emit("\nclass %s<bits<32> op, dag outs, dag ins, string opnstr,\n", name)
+		ectx.Emit("          list<dag> pattern = []>\n")
+		ectx.Emit("    : LAInst<outs, ins, deriveInsnMnemonic<NAME>.ret, opnstr, pattern> {\n")
+
+		for _, a := range f.Args {
+			ectx.Emit("  bits<%d> %s;\n", a.TotalWidth(), fieldName(f, mask, a))
+		}
+		if len(f.Args) > 0 {
+			ectx.Emit("\n")
+		}
+
+		ectx.Emit("  let Inst{31-0} = op;\n")
+		for _, a := range f.Args {
+			name := fieldName(f, mask, a)
+			for _, p := range a.SlotParts() {
+				s := p.Slot
+				ectx.Emit(
+					"  let Inst{%d-%d} = %s{%d-%d};\n",
+					s.MSB(),
+					s.Offset,
+					name,
+					p.Shift+s.Width-1,
+					p.Shift,
+				)
+			}
+		}
+
+		ectx.Emit("}\n")
+	}
+}
+
+// predicatesForInsn returns the LLVM predicates required by the insn.
+func predicatesForInsn(d *common.InsnDescription) []string {
+	switch d.Ext {
+	case common.ExtLSX:
+		return []string{"HasExtLSX"}
+	case common.ExtLASX:
+		return []string{"HasExtLASX"}
+	case common.ExtLBT:
+		return []string{"HasExtLBT"}
+	case common.ExtLVZ:
+		return []string{"HasExtLVZ"}
+	}
+
+	if d.Width == common.WidthLA64 {
+		return []string{"IsLA64"}
+	}
+	return nil
+}
+
+// recordName returns the name of the record of the insn, from which the
+// mnemonic is derived by LLVM, e.g. "AMSWAP__DB_W" for "amswap_db.w".
+func recordName(mnemonic string) string {
+	s := strings.ReplaceAll(mnemonic, "_", "__")
+	s = strings.ReplaceAll(s, ".", "_")
+	s = strings.ToUpper(s)
+
+	roundtrip := (&common.TableGenInsn{Name: s}).Mnemonic()
+	if roundtrip != mnemonic {
+		panic(fmt.Sprintf("mnemonic %s cannot be derived from record name %s", mnemonic, s))
+	}
+
+	return s
+}
+
+func emitInsnDefs(ectx *common.EmitterCtx, descs []*common.InsnDescription) {
+	ectx.Emit("\n//===----------------------------------------------------------------------===//\n")
+	ectx.Emit("// Instructions\n")
+	ectx.Emit("//===----------------------------------------------------------------------===//\n")
+
+	seen := make(map[string]string)
+	currentPredicates := "<none>"
+	for _, d := range descs {
+		predicates := strings.Join(predicatesForInsn(d), ", ")
+		if predicates != currentPredicates {
+			if currentPredicates != "<none>" && currentPredicates != "" {
+				ectx.Emit("} // Predicates = [%s]\n", currentPredicates)
+			}
+
+			ectx.Emit("\n")
+			if predicates != "" {
+				ectx.Emit("let Predicates = [%s] in {\n", predicates)
+			}
+			currentPredicates = predicates
+		}
+
+		mnemonic := d.Mnemonic
+		if *manual {
+			mnemonic = d.ManualMnemonic()
+		}
+
+		name := recordName(mnemonic)
+		if prev, ok := seen[name]; ok {
+			panic(fmt.Sprintf("record name %s shared by %s and %s", name, prev, mnemonic))
+		}
+		seen[name] = mnemonic
+
+		ops, opnstr := insnOperands(d)
+
+		ins := "(ins)"
+		if ops != "" {
+			ins = "(ins " + ops + ")"
+		}
+
+		ectx.Emit(
+			"def %s : %s<0x%08x, (outs), %s, \"%s\">;\n",
+			name,
+			formatClassName(d.Format),
+			d.Word,
+			ins,
+			opnstr,
+		)
+	}
+
+	if currentPredicates != "<none>" && currentPredicates != "" {
+		ectx.Emit("} // Predicates = [%s]\n", currentPredicates)
+	}
+}
+
+// insnOperands returns the operand list and operand string of the insn,
+// e.g. "GPR:$rd, GPR:$rj, simm12:$imm12" and "$rd, $rj, $imm12".
+func insnOperands(d *common.InsnDescription) (string, string) {
+	args := d.PostprocessedFormat().Args
+	if *manual {
+		args = d.ManualFormat().Args
+	}
+
+	ops := make([]string, len(args))
+	refs := make([]string, len(args))
+	for i, a := range args {
+		// the canonical arg provides the field name, and the postprocessed
+		// one the operand type
+		var canonicalArg, ppArg *common.Arg
+		for j, ca := range d.Format.Args {
+			if ca.Bitmask() == a.Bitmask() {
+				canonicalArg = ca
+				ppArg = d.PostprocessedFormat().Args[j]
+				break
+			}
+		}
+
+		typ := operandType(ppArg)
+		if !a.Kind.IsImm() && ppArg.Kind.IsImm() {
+			// FCSR operands are integer registers in the manual syntax
+			typ = "FCSR"
+		}
+
+		name := fieldName(d.Format, fcsrMask(d), canonicalArg)
+		ops[i] = typ + ":$" + name
+		refs[i] = "$" + name
+	}
+
+	return strings.Join(ops, ", "), strings.Join(refs, ", ")
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/loongson-community/loongarch-opcodes/scripts/go/common"
+)
+
+// Checks all insn description files for conflicts that cannot be detected
+// by looking at one line at a time.
+func main() {
+	descs, err := common.ReadAllInsnDescs(common.TablesDir)
+	if err != nil {
+		var pes common.ParseErrors
+		if errors.As(err, &pes) {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		panic(err)
+	}
+
+	sort.SliceStable(descs, func(i int, j int) bool {
+		return descs[i].Word < descs[j].Word
+	})
+
+	problems := checkSubsets(descs)
+	problems = append(problems, checkDuplicateMnemonics(descs, "mnemonic", canonicalMnemonic)...)
+	problems = append(problems, checkDuplicateMnemonics(descs, "manual mnemonic", manualMnemonic)...)
+	problems = append(problems, checkEncodingConflicts(descs)...)
+
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+}
+
+func describe(d *common.InsnDescription) string {
+	return fmt.Sprintf(
+		"%s: %08x %s %s",
+		d.Source,
+		d.Word,
+		d.Mnemonic,
+		d.Format.CanonicalRepr(),
+	)
+}
+
+// checkSubsets reports files whose names do not tell the extension of the
+// insns described within.
+func checkSubsets(descs []*common.InsnDescription) []string {
+	reported := make(map[string]bool)
+
+	var result []string
+	for _, d := range descs {
+		if d.Ext != common.ExtUnknown || reported[d.Source] {
+			continue
+		}
+
+		_, _, err := common.SubsetForPath(d.Source)
+		result = append(result, fmt.Sprintf("unrecognized table file name: %v", err))
+		reported[d.Source] = true
+	}
+
+	return result
+}
+
+func canonicalMnemonic(d *common.InsnDescription) string {
+	return d.Mnemonic
+}
+
+func manualMnemonic(d *common.InsnDescription) string {
+	return d.ManualMnemonic()
+}
+
+func checkDuplicateMnemonics(
+	descs []*common.InsnDescription,
+	kind string,
+	mnemonicFn func(*common.InsnDescription) string,
+) []string {
+	seen := make(map[string]*common.InsnDescription)
+
+	var result []string
+	for _, d := range descs {
+		mnemonic := mnemonicFn(d)
+		if prev, ok := seen[mnemonic]; ok {
+			result = append(result, fmt.Sprintf(
+				"duplicate %s %s:\n\t%s\n\t%s",
+				kind,
+				mnemonic,
+				describe(prev),
+				describe(d),
+			))
+			continue
+		}
+
+		seen[mnemonic] = d
+	}
+
+	return result
+}
+
+func checkEncodingConflicts(descs []*common.InsnDescription) []string {
+	var result []string
+	for i, a := range descs {
+		for _, b := range descs[i+1:] {
+			if !a.Overlaps(b) {
+				continue
+			}
+
+			kind := "ambiguous encodings"
+			if a.Word == b.Word {
+				kind = "duplicate insn words"
+			}
+
+			result = append(result, fmt.Sprintf("%s:\n\t%s\n\t%s", kind, describe(a), describe(b)))
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,264 @@
+// Package registers describes the register banks referenced by the insn
+// format notation, and the assembly names of their registers.
+package registers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Class int
+
+const (
+	ClassUnknown Class = 0
+	// General-purpose registers, "D" "J" "K" "A" in insn formats.
+	ClassGPR Class = 1
+	// Floating-point registers, "F" in insn formats.
+	ClassFPR Class = 2
+	// Floating-point condition codes, "C" in insn formats.
+	ClassFCC Class = 3
+	// LBT scratch registers, "T" in insn formats.
+	ClassScratch Class = 4
+	// LSX 128-bit vector registers, "V" in insn formats.
+	ClassLSX Class = 5
+	// LASX 256-bit vector registers, "X" in insn formats.
+	ClassLASX Class = 6
+	// FP control and status registers, unsigned immediates in insn formats
+	// but registers in the manual syntax. Only $fcsr0 to $fcsr3 are defined
+	// by the ISA.
+	ClassFCSR Class = 7
+)
+
+var AllClasses = []Class{
+	ClassGPR,
+	ClassFPR,
+	ClassFCC,
+	ClassScratch,
+	ClassLSX,
+	ClassLASX,
+	ClassFCSR,
+}
+
+type classInfo struct {
+	desc       string
+	prefix     string
+	goPrefix   string
+	fieldWidth uint
+	size       uint
+	abiNames   []string
+	aliases    map[string]int
+	// count is the number of registers, if fewer than the fields can refer
+	// to
+	count int
+}
+
+var classInfos = map[Class]*classInfo{
+	ClassGPR: {
+		desc:       "integer register",
+		prefix:     "$r",
+		goPrefix:   "R",
+		fieldWidth: 5,
+		size:       64,
+		abiNames: []string{
+			"$zero", "$ra", "$tp", "$sp", "$a0", "$a1", "$a2", "$a3",
+			"$a4", "$a5", "$a6", "$a7", "$t0", "$t1", "$t2", "$t3",
+			"$t4", "$t5", "$t6", "$t7", "$t8", "", "$fp", "$s0",
+			"$s1", "$s2", "$s3", "$s4", "$s5", "$s6", "$s7", "$s8",
+		},
+		aliases: map[string]int{
+			"$s9": 22,
+			// deprecated names of return value registers
+			"$v0": 4,
+			"$v1": 5,
+		},
+	},
+	ClassFPR: {
+		desc:       "FP register",
+		prefix:     "$f",
+		goPrefix:   "F",
+		fieldWidth: 5,
+		size:       64,
+		abiNames: []string{
+			"$fa0", "$fa1", "$fa2", "$fa3", "$fa4", "$fa5", "$fa6", "$fa7",
+			"$ft0", "$ft1", "$ft2", "$ft3", "$ft4", "$ft5", "$ft6", "$ft7",
+			"$ft8", "$ft9", "$ft10", "$ft11", "$ft12", "$ft13", "$ft14", "$ft15",
+			"$fs0", "$fs1", "$fs2", "$fs3", "$fs4", "$fs5", "$fs6", "$fs7",
+		},
+		aliases: map[string]int{
+			// deprecated names of return value registers
+			"$fv0": 0,
+			"$fv1": 1,
+		},
+	},
+	ClassFCC: {
+		desc:       "FCC register",
+		prefix:     "$fcc",
+		goPrefix:   "FCC",
+		fieldWidth: 3,
+		size:       1,
+	},
+	ClassScratch: {
+		desc:       "scratch register",
+		prefix:     "$scr",
+		goPrefix:   "SCR",
+		fieldWidth: 2,
+		size:       64,
+	},
+	ClassLSX: {
+		desc:       "LSX register",
+		prefix:     "$vr",
+		goPrefix:   "V",
+		fieldWidth: 5,
+		size:       128,
+	},
+	ClassLASX: {
+		desc:       "LASX register",
+		prefix:     "$xr",
+		goPrefix:   "X",
+		fieldWidth: 5,
+		size:       256,
+	},
+	ClassFCSR: {
+		desc:       "FCSR register",
+		prefix:     "$fcsr",
+		goPrefix:   "FCSR",
+		fieldWidth: 5,
+		count:      4,
+		size:       32,
+	},
+}
+
+func (c Class) info() *classInfo {
+	info, ok := classInfos[c]
+	if !ok {
+		panic(fmt.Sprintf("unknown register class: %d", c))
+	}
+	return info
+}
+
+func (c Class) String() string {
+	return c.info().desc
+}
+
+// FieldWidth returns the width in bits of insn fields referring to
+// registers of the class.
+func (c Class) FieldWidth() uint {
+	return c.info().fieldWidth
+}
+
+// Size returns the width in bits of registers of the class.
+func (c Class) Size() uint {
+	return c.info().size
+}
+
+// Count returns the number of registers in the class.
+func (c Class) Count() int {
+	if count := c.info().count; count != 0 {
+		return count
+	}
+	return 1 << c.FieldWidth()
+}
+
+// Prefix returns the prefix of numbered register names of the class, such
+// as "$r" in "$r4".
+func (c Class) Prefix() string {
+	return c.info().prefix
+}
+
+// Reg returns the register of the class with the given index.
+func (c Class) Reg(idx int) Register {
+	if idx < 0 || idx >= c.Count() {
+		panic(fmt.Sprintf("%s index out of range: %d", c, idx))
+	}
+
+	return Register{
+		Class: c,
+		Index: idx,
+	}
+}
+
+// Parse parses a register name of the class, accepting the numbered name,
+// the ABI name and any alias. Numbers must be written without leading zeros,
+// e.g. "$r1" but not "$r01".
+func (c Class) Parse(name string) (Register, error) {
+	info := c.info()
+
+	if strings.HasPrefix(name, info.prefix) {
+		num := name[len(info.prefix):]
+		idx, err := strconv.ParseUint(num, 10, 8)
+		if err == nil && int(idx) < c.Count() && strconv.FormatUint(idx, 10) == num {
+			return c.Reg(int(idx)), nil
+		}
+	}
+
+	for idx, abiName := range info.abiNames {
+		if abiName != "" && abiName == name {
+			return c.Reg(idx), nil
+		}
+	}
+
+	if idx, ok := info.aliases[name]; ok {
+		return c.Reg(idx), nil
+	}
+
+	return Register{}, fmt.Errorf("invalid %s name %s", info.desc, strconv.Quote(name))
+}
+
+// Parse parses a register name of any class.
+func Parse(name string) (Register, error) {
+	for _, c := range AllClasses {
+		r, err := c.Parse(name)
+		if err == nil {
+			return r, nil
+		}
+	}
+
+	return Register{}, fmt.Errorf("invalid register name %s", strconv.Quote(name))
+}
+
+type Register struct {
+	Class Class
+	Index int
+}
+
+func (r Register) String() string {
+	return r.Name()
+}
+
+// Name returns the numbered name of the register, e.g. "$r4".
+func (r Register) Name() string {
+	return r.Class.Prefix() + strconv.Itoa(r.Index)
+}
+
+// ABIName returns the ABI name of the register, e.g. "$a0", or the numbered
+// name if the register has none.
+func (r Register) ABIName() string {
+	abiNames := r.Class.info().abiNames
+	if r.Index < len(abiNames) && abiNames[r.Index] != "" {
+		return abiNames[r.Index]
+	}
+	return r.Name()
+}
+
+// IsReserved reports whether the register is reserved by the psABI, and
+// not to be allocated.
+func (r Register) IsReserved() bool {
+	return r.Class == ClassGPR && r.Index == 21
+}
+
+// GoName returns the name of the register in Go assembly syntax, e.g. "R4".
+//
+// The Go assembler only accepts "SP" for $r3, and "g" for $r22, which holds
+// the current goroutine.
+func (r Register) GoName() string {
+	if r.Class == ClassGPR {
+		switch r.Index {
+		case 3:
+			return "SP"
+		case 22:
+			return "g"
+		}
+	}
+	return r.Class.info().goPrefix + strconv.Itoa(r.Index)
+}
@@ -0,0 +1,96 @@
+package registers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterNames(t *testing.T) {
+	testcases := []struct {
+		r               Register
+		expectedName    string
+		expectedABIName string
+		expectedGoName  string
+	}{
+		{ClassGPR.Reg(0), "$r0", "$zero", "R0"},
+		{ClassGPR.Reg(3), "$r3", "$sp", "SP"},
+		{ClassGPR.Reg(4), "$r4", "$a0", "R4"},
+		{ClassGPR.Reg(21), "$r21", "$r21", "R21"},
+		{ClassGPR.Reg(22), "$r22", "$fp", "g"},
+		{ClassGPR.Reg(31), "$r31", "$s8", "R31"},
+		{ClassFPR.Reg(0), "$f0", "$fa0", "F0"},
+		{ClassFPR.Reg(23), "$f23", "$ft15", "F23"},
+		{ClassFPR.Reg(31), "$f31", "$fs7", "F31"},
+		{ClassFCC.Reg(7), "$fcc7", "$fcc7", "FCC7"},
+		{ClassScratch.Reg(3), "$scr3", "$scr3", "SCR3"},
+		{ClassLSX.Reg(31), "$vr31", "$vr31", "V31"},
+		{ClassLASX.Reg(1), "$xr1", "$xr1", "X1"},
+		{ClassFCSR.Reg(3), "$fcsr3", "$fcsr3", "FCSR3"},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.expectedName, tc.r.Name())
+		assert.Equal(t, tc.expectedABIName, tc.r.ABIName())
+		assert.Equal(t, tc.expectedGoName, tc.r.GoName())
+
+		// names must round-trip
+		for _, name := range []string{tc.r.Name(), tc.r.ABIName()} {
+			actual, err := tc.r.Class.Parse(name)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.r, actual)
+
+			actual, err = Parse(name)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.r, actual)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	testcases := []struct {
+		c        Class
+		name     string
+		ok       bool
+		expected Register
+	}{
+		{ClassGPR, "$s9", true, ClassGPR.Reg(22)},
+		{ClassGPR, "$v1", true, ClassGPR.Reg(5)},
+		{ClassFPR, "$fv0", true, ClassFPR.Reg(0)},
+		{ClassGPR, "$r32", false, Register{}},
+		{ClassGPR, "r1", false, Register{}},
+		{ClassGPR, "$f1", false, Register{}},
+		{ClassFPR, "$fcc1", false, Register{}},
+		{ClassFCC, "$fcc8", false, Register{}},
+		{ClassScratch, "$scr4", false, Register{}},
+		{ClassLSX, "$xr0", false, Register{}},
+		{ClassFCSR, "$fcsr3", true, ClassFCSR.Reg(3)},
+		{ClassFCSR, "$fcsr4", false, Register{}},
+		{ClassFCSR, "$fcsr31", false, Register{}},
+		{ClassGPR, "$r01", false, Register{}},
+		{ClassGPR, "$r00", false, Register{}},
+		{ClassFPR, "$f+1", false, Register{}},
+		{ClassFCC, "$fcc07", false, Register{}},
+	}
+
+	for _, tc := range testcases {
+		actual, err := tc.c.Parse(tc.name)
+		if tc.ok {
+			assert.NoError(t, err, tc.name)
+			assert.Equal(t, tc.expected, actual, tc.name)
+		} else {
+			assert.Error(t, err, tc.name)
+		}
+	}
+}
+
+func TestClass(t *testing.T) {
+	assert.Equal(t, 32, ClassGPR.Count())
+	assert.Equal(t, 8, ClassFCC.Count())
+	assert.Equal(t, 4, ClassScratch.Count())
+	assert.Equal(t, uint(2), ClassScratch.FieldWidth())
+	assert.Equal(t, 4, ClassFCSR.Count())
+	assert.Equal(t, uint(5), ClassFCSR.FieldWidth())
+	assert.Equal(t, uint(128), ClassLSX.Size())
+	assert.Equal(t, uint(256), ClassLASX.Size())
+}